@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kops/cmd/kops/util"
+	"k8s.io/kops/pkg/commands"
+)
+
+// NewCmdBastion returns the `kops bastion` command, grouping the up/down
+// subcommands that scale the bastion ASG(s) on demand, independent of any
+// BastionAutoShutdown schedule.
+//
+// NewCmdBastion is not yet added to the root command: this tree has no root
+// command file to add it to. It is only reachable by calling it directly
+// until that wiring exists.
+func NewCmdBastion(f *util.Factory, out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bastion",
+		Short: "Scale the bastion up or down on demand",
+	}
+
+	cmd.AddCommand(NewCmdBastionUp(f, out))
+	cmd.AddCommand(NewCmdBastionDown(f, out))
+
+	return cmd
+}
+
+// BastionScaleOptions holds the flags shared by `kops bastion up` and `kops bastion down`.
+type BastionScaleOptions struct {
+	ClusterName string
+	// Region is the AWS region the bastion ASG(s) live in, used to call the Auto
+	// Scaling API directly. kOps' own ClusterSpec doesn't carry a region, so this
+	// must be passed explicitly.
+	Region string
+}
+
+// NewCmdBastionUp returns the `kops bastion up` command.
+func NewCmdBastionUp(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &BastionScaleOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "up",
+		Short: "Scale the bastion instance group(s) up to their configured MinSize",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.ClusterName = rootCommand.clusterName
+			return runBastionScale(context.Background(), f, out, options, true)
+		},
+	}
+	cmd.Flags().StringVar(&options.Region, "region", options.Region, "AWS region the bastion ASG(s) run in")
+	return cmd
+}
+
+// NewCmdBastionDown returns the `kops bastion down` command.
+func NewCmdBastionDown(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &BastionScaleOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "down",
+		Short: "Scale the bastion instance group(s) down to 0",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.ClusterName = rootCommand.clusterName
+			return runBastionScale(context.Background(), f, out, options, false)
+		},
+	}
+	cmd.Flags().StringVar(&options.Region, "region", options.Region, "AWS region the bastion ASG(s) run in")
+	return cmd
+}
+
+// runBastionScale scales the cluster's bastion ASG(s) directly via the AWS API, the
+// on-demand path `kops bastion up`/`down` exists for. Unlike a normal InstanceGroup
+// edit, this never touches the kOps API or requires `kops update cluster --yes`.
+func runBastionScale(ctx context.Context, f *util.Factory, out io.Writer, options *BastionScaleOptions, up bool) error {
+	if options.Region == "" {
+		return fmt.Errorf("--region is required")
+	}
+
+	clientset, err := f.KopsClient()
+	if err != nil {
+		return err
+	}
+
+	cluster, err := clientset.GetCluster(ctx, options.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	instanceGroups, err := clientset.InstanceGroupsFor(cluster).List(ctx)
+	if err != nil {
+		return err
+	}
+
+	bastions, err := commands.SetBastionDesiredCapacity(ctx, options.Region, cluster, instanceGroups, up)
+	if err != nil {
+		return err
+	}
+
+	verb := "up"
+	if !up {
+		verb = "down"
+	}
+	fmt.Fprintf(out, "scaled %d bastion ASG(s) %s\n", len(bastions), verb)
+	return nil
+}
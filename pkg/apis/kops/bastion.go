@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kops
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BastionSpec configures the bastion host(s) kOps creates in the utility
+// subnets of a private-topology cluster.
+type BastionSpec struct {
+	// BastionPublicName, if set, is the DNS name kOps creates a CNAME/A
+	// record for, pointing at the bastion's public entry point.
+	BastionPublicName string
+	// IdleTimeoutSeconds overrides the Classic ELB's idle connection timeout.
+	IdleTimeoutSeconds *int64
+	// LoadBalancer configures the load balancer (or lack of one) fronting
+	// the bastion.
+	LoadBalancer *BastionLoadBalancerSpec
+	// AccessMode selects how operators reach the bastion. The empty value is
+	// equivalent to BastionAccessModeSSHELB.
+	AccessMode BastionAccessMode
+	// AutoShutdown scales the bastion ASG(s) down to 0 on a schedule, or after
+	// a period of inactivity, and back up on demand.
+	AutoShutdown *BastionAutoShutdownSpec
+	// SSHAccess is the allowlist of sources permitted to SSH to the bastion,
+	// each as a CIDR, a prefix list, or a security group. If empty,
+	// ClusterSpec.SSHAccess is used as a CIDR-only fallback.
+	SSHAccess []SSHAccessSource
+}
+
+// SSHAccessSource is a single entry in a bastion's SSH allowlist. Exactly one
+// of CIDR, PrefixListID, or SecurityGroupID should be set.
+type SSHAccessSource struct {
+	// CIDR is an IPv4 or IPv6 CIDR allowed to reach the bastion.
+	CIDR string
+	// PrefixListID is an AWS-managed or customer-managed prefix list allowed
+	// to reach the bastion.
+	PrefixListID string
+	// SecurityGroupID is an existing, user-managed security group allowed to
+	// reach the bastion.
+	SecurityGroupID string
+}
+
+// BastionAutoShutdownSpec configures automatic scaling of the bastion ASG(s)
+// to 0 instances when nobody needs a bastion running.
+type BastionAutoShutdownSpec struct {
+	// ScaleDownSchedule is a cron expression, in UTC, on which the bastion
+	// ASG(s) are scaled to 0. Must be set together with ScaleUpSchedule.
+	ScaleDownSchedule string
+	// ScaleUpSchedule is a cron expression, in UTC, on which the bastion
+	// ASG(s) are scaled back up to their configured size. Must be set
+	// together with ScaleDownSchedule.
+	ScaleUpSchedule string
+	// IdleAfter, if set, scales the bastion ASG down to 0 once the Classic
+	// ELB has reported no requests for this long. Requires
+	// LoadBalancer.Type: Classic.
+	IdleAfter *metav1.Duration
+}
+
+// BastionAccessMode selects how operators reach the bastion instances.
+type BastionAccessMode string
+
+const (
+	// BastionAccessModeSSHELB is the default: operators SSH directly to the
+	// bastion, arriving through whatever LoadBalancer fronts it.
+	BastionAccessModeSSHELB BastionAccessMode = "SSHELB"
+	// BastionAccessModeSSMSessionManager routes access through AWS Systems
+	// Manager Session Manager instead of port 22, so the bastion needs no
+	// inbound SSH rule at all.
+	BastionAccessModeSSMSessionManager BastionAccessMode = "SSMSessionManager"
+	// BastionAccessModeEC2InstanceConnect allows SSH only from the AWS-managed
+	// EC2 Instance Connect service prefix list, so operators authenticate via
+	// `aws ec2-instance-connect send-ssh-public-key` rather than a long-lived
+	// allowlisted CIDR.
+	BastionAccessModeEC2InstanceConnect BastionAccessMode = "EC2InstanceConnect"
+)
+
+// BastionLoadBalancerType selects what, if anything, fronts the bastion ASG.
+type BastionLoadBalancerType string
+
+const (
+	// BastionLoadBalancerTypeClassic is the default: a Classic ELB with a
+	// public SSH listener.
+	BastionLoadBalancerTypeClassic BastionLoadBalancerType = "Classic"
+	// BastionLoadBalancerTypeNetwork fronts the bastion with an NLB instead,
+	// for clusters that want a static per-AZ IP or TLS passthrough semantics
+	// closer to a plain TCP proxy.
+	BastionLoadBalancerTypeNetwork BastionLoadBalancerType = "Network"
+	// BastionLoadBalancerTypeNone runs no load balancer at all; the bastion
+	// gets a static Elastic IP instead.
+	BastionLoadBalancerTypeNone BastionLoadBalancerType = "None"
+)
+
+// BastionLoadBalancerSpec configures the load balancer fronting the bastion.
+type BastionLoadBalancerSpec struct {
+	// Type selects Classic (the default), Network, or None.
+	Type BastionLoadBalancerType
+	// AdditionalSecurityGroups are extra, user-managed security groups
+	// attached to the load balancer alongside the one kOps manages.
+	AdditionalSecurityGroups []string
+	// CrossZoneLoadBalancing enables cross-zone load balancing on the
+	// Classic ELB, so traffic is spread across the bastion instances in
+	// every zone rather than hashed to whichever zone received the
+	// connection. Defaults to true.
+	CrossZoneLoadBalancing *bool
+	// AccessLog configures access logging on the Classic ELB.
+	AccessLog *BastionAccessLogSpec
+}
+
+// BastionAccessLogSpec configures access logging on the bastion's Classic ELB.
+type BastionAccessLogSpec struct {
+	// Enabled turns access logging on or off.
+	Enabled *bool
+	// S3BucketName is the bucket access logs are delivered to.
+	S3BucketName string
+	// S3BucketPrefix is the prefix within S3BucketName that logs are written under.
+	S3BucketPrefix string
+	// EmitInterval is how often, in minutes, the ELB publishes a log file. AWS
+	// only accepts 5 or 60.
+	EmitInterval *int64
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kops
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Cluster represents a kOps cluster.
+type Cluster struct {
+	metav1.ObjectMeta
+
+	Spec ClusterSpec
+}
+
+// ClusterSpec is the client-visible configuration for a cluster.
+type ClusterSpec struct {
+	// CloudProvider is the name of the cloud provider running the cluster.
+	CloudProvider string
+	// MasterInternalName is the internal DNS name for the masters.
+	MasterInternalName string
+	// UseHostCertificates, if set, mounts /etc/ssl/certs from the host into
+	// system components that need to validate TLS certificates.
+	UseHostCertificates *bool
+	// SSHAccess is the legacy CIDR-only SSH allowlist, superseded for the
+	// bastion by Topology.Bastion.SSHAccess but still read as a fallback.
+	SSHAccess []string
+	// CloudLabels are tags applied to all cloud resources kOps creates.
+	CloudLabels map[string]string
+	// EtcdClusters holds configuration for each etcd cluster.
+	EtcdClusters []EtcdClusterSpec
+	// Monitoring configures cluster-wide monitoring integrations.
+	Monitoring *MonitoringSpec
+	// Topology configures the cluster's network/instance topology.
+	Topology *Topology
+}
+
+// Topology configures the cluster's network/instance topology.
+type Topology struct {
+	// Bastion configures the bastion host(s), if any, in a private-topology
+	// cluster.
+	Bastion *BastionSpec
+}
+
+// MonitoringSpec configures cluster-wide monitoring integrations.
+type MonitoringSpec struct {
+	Prometheus *PrometheusMonitoringSpec
+}
+
+// PrometheusMonitoringSpec configures kube-prometheus-stack integration, e.g.
+// emitting a ServiceMonitor for components that expose Prometheus metrics.
+type PrometheusMonitoringSpec struct {
+	Enabled bool
+}
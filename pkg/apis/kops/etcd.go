@@ -0,0 +1,167 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kops
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// CloudProviderID is the kOps identifier for a supported cloud.
+type CloudProviderID string
+
+const (
+	CloudProviderAWS       CloudProviderID = "aws"
+	CloudProviderGCE       CloudProviderID = "gce"
+	CloudProviderDO        CloudProviderID = "digitalocean"
+	CloudProviderAzure     CloudProviderID = "azure"
+	CloudProviderALI       CloudProviderID = "alicloud"
+	CloudProviderOpenstack CloudProviderID = "openstack"
+)
+
+// EtcdProviderType determines how the etcd cluster members are managed.
+type EtcdProviderType string
+
+const (
+	EtcdProviderTypeManager EtcdProviderType = "Manager"
+)
+
+// EtcdMemberSpec is the specification for a single etcd member.
+type EtcdMemberSpec struct {
+	Name           string
+	InstanceGroup  *string
+}
+
+// EtcdManagerSpec controls the etcd-manager binary itself, as opposed to the
+// etcd process it supervises.
+type EtcdManagerSpec struct {
+	Image                 string
+	LogLevel              *int32
+	DiscoveryPollInterval *string
+	Env                   []EnvVar
+	// VolumeMode selects how etcd-manager obtains its data volume. The empty
+	// value preserves the historical behavior of etcd-manager attaching a
+	// cloud block device itself; EtcdManagerVolumeModeCSI instead expects a
+	// CSI-backed PersistentVolumeClaim to already exist for the pod.
+	VolumeMode string
+}
+
+const (
+	// EtcdManagerVolumeModeCSI runs etcd-manager against a CSI-provisioned
+	// PersistentVolumeClaim instead of letting it attach a cloud volume
+	// itself, so it no longer needs host-level privileges to run.
+	EtcdManagerVolumeModeCSI = "CSI"
+)
+
+// EnvVar is a name/value pair forwarded into a managed component's container.
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+// EtcdBackupSpec configures how etcd-manager (and, for destinations it can't
+// reach directly, the backup sidecar) backs up an etcd cluster.
+type EtcdBackupSpec struct {
+	// BackupStore is the primary backup location understood natively by
+	// etcd-manager's own --backup-store flag.
+	BackupStore string
+	// FullInterval is how often etcd-manager takes a snapshot to BackupStore,
+	// and how often the backup sidecar takes one for Destinations unless
+	// IncrementalInterval is set.
+	FullInterval *metav1.Duration
+	// IncrementalInterval, if set, replaces FullInterval as the backup
+	// sidecar's snapshot interval for Destinations. The sidecar always saves a
+	// full etcdctl snapshot; no incremental snapshots are taken. etcd-manager's
+	// own --backup-store ignores this field.
+	IncrementalInterval *metav1.Duration
+	// Retention controls how long backups are kept before being pruned.
+	Retention *EtcdBackupRetentionSpec
+	// Destinations are additional backup locations, beyond BackupStore, that
+	// the backup sidecar fans out to.
+	Destinations []string
+	// Encryption configures at-rest encryption of the snapshots uploaded to
+	// Destinations.
+	Encryption *EtcdBackupEncryptionSpec
+}
+
+// EtcdBackupRetentionSpec bounds how many/how old backups are kept.
+type EtcdBackupRetentionSpec struct {
+	// Count is the maximum number of backups to retain.
+	Count *int32
+	// MaxAge is the maximum age of a backup before it is pruned.
+	MaxAge *metav1.Duration
+}
+
+// EtcdBackupEncryptionMode selects how snapshots are encrypted before being
+// uploaded to Destinations.
+type EtcdBackupEncryptionMode string
+
+const (
+	EtcdBackupEncryptionModeAESGCM EtcdBackupEncryptionMode = "AES-GCM"
+	EtcdBackupEncryptionModeKMS    EtcdBackupEncryptionMode = "KMS"
+)
+
+// EtcdBackupEncryptionSpec configures at-rest encryption for backup snapshots.
+type EtcdBackupEncryptionSpec struct {
+	Mode EtcdBackupEncryptionMode
+	// KMSKeyID is the key used to encrypt/decrypt when Mode is KMS.
+	KMSKeyID string
+	// PassphraseSecretName is the name of the kube-system Secret (with a "passphrase"
+	// key) used to encrypt/decrypt when Mode is AES-GCM. Required when Mode is AES-GCM.
+	PassphraseSecretName string
+}
+
+// EtcdClusterSpec is the specification for a single etcd cluster.
+type EtcdClusterSpec struct {
+	// Name is the name of the etcd cluster, e.g. "main" or "events".
+	Name string
+	// Provider selects the etcd management strategy.
+	Provider EtcdProviderType
+	// Version is the etcd version to run.
+	Version string
+	// Members holds the per-master etcd member configuration.
+	Members []EtcdMemberSpec
+	// Manager configures etcd-manager itself.
+	Manager *EtcdManagerSpec
+	// Backups configures where and how often etcd is backed up.
+	Backups *EtcdBackupSpec
+	// CPURequest/MemoryRequest size the etcd-manager container.
+	CPURequest    *resource.Quantity
+	MemoryRequest *resource.Quantity
+
+	// LeaderElectionTimeout overrides etcd's election timeout.
+	LeaderElectionTimeout *metav1.Duration
+	// HeartbeatInterval overrides etcd's heartbeat interval.
+	HeartbeatInterval *metav1.Duration
+	// SnapshotCount overrides the number of applied Raft entries etcd
+	// retains before triggering a snapshot.
+	SnapshotCount *int64
+	// QuotaBackendBytes overrides etcd's storage size limit.
+	QuotaBackendBytes *int64
+	// MaxRequestBytes overrides etcd's maximum client request size.
+	MaxRequestBytes *int64
+	// AutoCompactionMode selects "periodic" or "revision" auto-compaction.
+	AutoCompactionMode *string
+	// AutoCompactionRetention sets the retention window/revision count
+	// for AutoCompactionMode.
+	AutoCompactionRetention *string
+
+	// Profile overrides the registry key used to look up the etcd cluster's
+	// port/CA allocation (see etcdmanager.RegisterEtcdClusterProfile). If
+	// empty, Name is used, which matches the built-in profiles.
+	Profile string
+}
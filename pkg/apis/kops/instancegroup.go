@@ -0,0 +1,45 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kops
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// InstanceGroupRole describes the role played by the instances in an InstanceGroup.
+type InstanceGroupRole string
+
+const (
+	InstanceGroupRoleMaster  InstanceGroupRole = "Master"
+	InstanceGroupRoleNode    InstanceGroupRole = "Node"
+	InstanceGroupRoleBastion InstanceGroupRole = "Bastion"
+)
+
+// InstanceGroup represents a group of instances with the same configuration,
+// e.g. the bastion ASG.
+type InstanceGroup struct {
+	metav1.ObjectMeta
+
+	Spec InstanceGroupSpec
+}
+
+// InstanceGroupSpec is the specification for an InstanceGroup.
+type InstanceGroupSpec struct {
+	Role    InstanceGroupRole
+	MinSize *int64
+	MaxSize *int64
+}
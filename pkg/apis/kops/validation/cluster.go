@@ -0,0 +1,34 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+// ValidateClusterSpec checks the parts of a ClusterSpec that can be validated
+// without talking to the cloud.
+func ValidateClusterSpec(spec *kops.ClusterSpec, fieldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i := range spec.EtcdClusters {
+		allErrs = append(allErrs, ValidateEtcdClusterSpec(&spec.EtcdClusters[i], fieldPath.Child("etcdClusters").Index(i))...)
+	}
+
+	return allErrs
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+// ValidateEtcdClusterSpec validates a single EtcdClusterSpec. It is called from
+// ValidateClusterSpec for every entry in ClusterSpec.EtcdClusters.
+func ValidateEtcdClusterSpec(spec *kops.EtcdClusterSpec, fieldPath *field.Path) field.ErrorList {
+	return validateEtcdTuning(spec, fieldPath)
+}
+
+// validateEtcdTuning checks the etcd timing/compaction overrides that are forwarded
+// to etcd-manager as ETCD_* environment variables.
+func validateEtcdTuning(spec *kops.EtcdClusterSpec, fieldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if spec.LeaderElectionTimeout != nil && spec.HeartbeatInterval != nil {
+		election := spec.LeaderElectionTimeout.Duration
+		heartbeat := spec.HeartbeatInterval.Duration
+		if election < 5*heartbeat {
+			allErrs = append(allErrs, field.Invalid(fieldPath.Child("leaderElectionTimeout"), spec.LeaderElectionTimeout.Duration.String(),
+				"leaderElectionTimeout must be at least 5x heartbeatInterval"))
+		}
+	}
+
+	if spec.SnapshotCount != nil && *spec.SnapshotCount <= 0 {
+		allErrs = append(allErrs, field.Invalid(fieldPath.Child("snapshotCount"), *spec.SnapshotCount, "snapshotCount must be greater than zero"))
+	}
+
+	if spec.QuotaBackendBytes != nil && *spec.QuotaBackendBytes <= 0 {
+		allErrs = append(allErrs, field.Invalid(fieldPath.Child("quotaBackendBytes"), *spec.QuotaBackendBytes, "quotaBackendBytes must be greater than zero"))
+	}
+
+	if spec.MaxRequestBytes != nil && *spec.MaxRequestBytes <= 0 {
+		allErrs = append(allErrs, field.Invalid(fieldPath.Child("maxRequestBytes"), *spec.MaxRequestBytes, "maxRequestBytes must be greater than zero"))
+	}
+
+	if spec.AutoCompactionMode != nil {
+		switch *spec.AutoCompactionMode {
+		case "periodic", "revision":
+		default:
+			allErrs = append(allErrs, field.NotSupported(fieldPath.Child("autoCompactionMode"), *spec.AutoCompactionMode, []string{"periodic", "revision"}))
+		}
+	}
+
+	return allErrs
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+// bastionAutoScalingGroupName returns the ASG name kOps creates for a bastion
+// instance group, the same "<ig-name>.<cluster-name>" convention the
+// BastionAutoShutdown scheduled actions are named after.
+func bastionAutoScalingGroupName(cluster *kops.Cluster, ig *kops.InstanceGroup) string {
+	return ig.ObjectMeta.Name + "." + cluster.ObjectMeta.Name
+}
+
+// SetBastionDesiredCapacity scales every bastion instance group's ASG up to its
+// configured MinSize, or down to 0, by calling the AWS Auto Scaling API directly -
+// the entire point of `kops bastion up`/`down` is to flip this in seconds, without
+// waiting on a full `kops update cluster --yes`. It never writes to the
+// InstanceGroup's own MinSize/MaxSize, so the operator's configured sizing is
+// unaffected by any number of up/down cycles.
+func SetBastionDesiredCapacity(ctx context.Context, region string, cluster *kops.Cluster, instanceGroups []*kops.InstanceGroup, up bool) ([]*kops.InstanceGroup, error) {
+	var bastions []*kops.InstanceGroup
+	for _, ig := range instanceGroups {
+		if ig.Spec.Role == kops.InstanceGroupRoleBastion {
+			bastions = append(bastions, ig)
+		}
+	}
+	if len(bastions) == 0 {
+		return nil, fmt.Errorf("cluster %q has no bastion instance groups", cluster.ObjectMeta.Name)
+	}
+
+	sess, err := session.NewSession(aws.NewConfig().WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("creating AWS session: %v", err)
+	}
+	client := autoscaling.New(sess)
+
+	for _, ig := range bastions {
+		desiredCapacity := int64(0)
+		if up {
+			desiredCapacity = int64(1)
+			if ig.Spec.MinSize != nil && *ig.Spec.MinSize > 0 {
+				desiredCapacity = *ig.Spec.MinSize
+			}
+		}
+
+		input := &autoscaling.UpdateAutoScalingGroupInput{
+			AutoScalingGroupName: aws.String(bastionAutoScalingGroupName(cluster, ig)),
+			DesiredCapacity:      aws.Int64(desiredCapacity),
+		}
+		// AWS rejects a DesiredCapacity outside the group's current MinSize..MaxSize, and a
+		// scheduled scale-down leaves both at 0, so the bounds are moved along with it:
+		// scaling up sets MinSize to the desired capacity and MaxSize to at least that,
+		// scaling down sets MinSize to 0. kOps' own AutoScalingScheduledAction tasks (if
+		// configured) overwrite these bounds again on their next run.
+		if up {
+			input.MinSize = aws.Int64(desiredCapacity)
+			maxSize := desiredCapacity
+			if ig.Spec.MaxSize != nil && *ig.Spec.MaxSize > maxSize {
+				maxSize = *ig.Spec.MaxSize
+			}
+			input.MaxSize = aws.Int64(maxSize)
+		} else {
+			input.MinSize = aws.Int64(0)
+		}
+
+		if _, err := client.UpdateAutoScalingGroupWithContext(ctx, input); err != nil {
+			return nil, fmt.Errorf("scaling bastion ASG %q: %v", aws.StringValue(input.AutoScalingGroupName), err)
+		}
+	}
+
+	return bastions, nil
+}
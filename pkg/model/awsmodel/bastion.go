@@ -17,6 +17,11 @@ limitations under the License.
 package awsmodel
 
 import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -30,6 +35,15 @@ const (
 	BastionELBDefaultIdleTimeout  = 5 * time.Minute
 )
 
+// bastionSSMVPCEndpointServices are the interface VPC endpoints a bastion needs to reach in
+// order for `aws ssm start-session` to work without any inbound SSH exposure at all.
+var bastionSSMVPCEndpointServices = []string{"ssm", "ssmmessages", "ec2messages"}
+
+// ec2InstanceConnectPrefixListName is the AWS-managed prefix list that covers the EC2
+// Instance Connect service, used as the sole SSH source when AccessMode is
+// EC2InstanceConnect so that the bastion never has to trust a user-supplied CIDR.
+const ec2InstanceConnectPrefixListName = "com.amazonaws.ec2-instance-connect"
+
 // BastionModelBuilder adds model objects to support bastions
 //
 // Bastion instances live in the utility subnets created in the private topology.
@@ -56,6 +70,62 @@ func (b *BastionModelBuilder) Build(c *fi.ModelBuilderContext) error {
 		return nil
 	}
 
+	lbType := kops.BastionLoadBalancerTypeClassic
+	if b.Cluster.Spec.Topology != nil && b.Cluster.Spec.Topology.Bastion != nil && b.Cluster.Spec.Topology.Bastion.LoadBalancer != nil && b.Cluster.Spec.Topology.Bastion.LoadBalancer.Type != "" {
+		lbType = b.Cluster.Spec.Topology.Bastion.LoadBalancer.Type
+	}
+
+	accessMode := kops.BastionAccessModeSSHELB
+	if b.Cluster.Spec.Topology != nil && b.Cluster.Spec.Topology.Bastion != nil && b.Cluster.Spec.Topology.Bastion.AccessMode != "" {
+		accessMode = b.Cluster.Spec.Topology.Bastion.AccessMode
+	}
+
+	var autoShutdown *kops.BastionAutoShutdownSpec
+	if b.Cluster.Spec.Topology != nil && b.Cluster.Spec.Topology.Bastion != nil {
+		autoShutdown = b.Cluster.Spec.Topology.Bastion.AutoShutdown
+	}
+
+	// Scale the bastion ASGs to 0 and back on a fixed schedule, independent of
+	// AccessMode or LoadBalancer.Type - this is the "nobody needs a bastion at 2am" case.
+	// The "idle for IdleAfter" case below additionally requires a Classic ELB, since it
+	// alarms on ELB request metrics.
+	if autoShutdown != nil && autoShutdown.ScaleDownSchedule != "" && autoShutdown.ScaleUpSchedule != "" {
+		for _, ig := range bastionInstanceGroups {
+			asg := b.LinkToAutoscalingGroup(ig)
+
+			scaleDown := &awstasks.AutoScalingScheduledAction{
+				Name:             fi.String("bastion-scale-down-" + ig.ObjectMeta.Name + "." + b.ClusterName()),
+				Lifecycle:        b.Lifecycle,
+				AutoscalingGroup: asg,
+				DesiredCapacity:  fi.Int64(0),
+				MinSize:          fi.Int64(0),
+				MaxSize:          fi.Int64(0),
+				Recurrence:       fi.String(autoShutdown.ScaleDownSchedule),
+			}
+			c.AddTask(scaleDown)
+
+			minSize := ig.Spec.MinSize
+			if minSize == nil {
+				minSize = fi.Int64(1)
+			}
+			// The scale-down action leaves the ASG's MaxSize at 0, so it is always set here.
+			maxSize := ig.Spec.MaxSize
+			if maxSize == nil {
+				maxSize = minSize
+			}
+			scaleUp := &awstasks.AutoScalingScheduledAction{
+				Name:             fi.String("bastion-scale-up-" + ig.ObjectMeta.Name + "." + b.ClusterName()),
+				Lifecycle:        b.Lifecycle,
+				AutoscalingGroup: asg,
+				DesiredCapacity:  minSize,
+				MinSize:          minSize,
+				MaxSize:          maxSize,
+				Recurrence:       fi.String(autoShutdown.ScaleUpSchedule),
+			}
+			c.AddTask(scaleUp)
+		}
+	}
+
 	bastionGroups, err := b.GetSecurityGroups(kops.InstanceGroupRoleBastion)
 	if err != nil {
 		return err
@@ -87,19 +157,44 @@ func (b *BastionModelBuilder) Build(c *fi.ModelBuilderContext) error {
 		AddDirectionalGroupRule(c, t)
 	}
 
-	// Allow incoming SSH traffic to bastions, through the ELB
-	// TODO: Could we get away without an ELB here?  Tricky to fix if dns-controller breaks though...
-	for _, dest := range bastionGroups {
-		t := &awstasks.SecurityGroupRule{
-			Name:          fi.String("ssh-elb-to-bastion" + dest.Suffix),
-			Lifecycle:     b.SecurityLifecycle,
-			SecurityGroup: dest.Task,
-			SourceGroup:   b.LinkToELBSecurityGroup(BastionELBSecurityGroupPrefix),
-			Protocol:      fi.String("tcp"),
-			FromPort:      fi.Int64(22),
-			ToPort:        fi.Int64(22),
+	// Allow incoming SSH traffic to bastions. SSMSessionManager needs no inbound SSH rule at
+	// all - sessions are brokered entirely through the SSM VPC endpoints added below.
+	// AccessMode is checked ahead of LoadBalancer.Type so that EC2InstanceConnect takes
+	// effect regardless of which load balancer (if any) fronts the bastion.
+	if accessMode == kops.BastionAccessModeSSMSessionManager {
+		// no-op: access is via aws ssm start-session, not port 22
+	} else if accessMode == kops.BastionAccessModeEC2InstanceConnect {
+		for _, dest := range bastionGroups {
+			t := &awstasks.SecurityGroupRule{
+				Name:               fi.String("eic-to-bastion" + dest.Suffix),
+				Lifecycle:          b.SecurityLifecycle,
+				SecurityGroup:      dest.Task,
+				Protocol:           fi.String("tcp"),
+				FromPort:           fi.Int64(22),
+				ToPort:             fi.Int64(22),
+				SourcePrefixListId: fi.String(ec2InstanceConnectPrefixListName),
+			}
+			AddDirectionalGroupRule(c, t)
+		}
+	} else if lbType != kops.BastionLoadBalancerTypeNone {
+		for _, dest := range bastionGroups {
+			t := &awstasks.SecurityGroupRule{
+				Name:          fi.String("ssh-elb-to-bastion" + dest.Suffix),
+				Lifecycle:     b.SecurityLifecycle,
+				SecurityGroup: dest.Task,
+				SourceGroup:   b.LinkToELBSecurityGroup(BastionELBSecurityGroupPrefix),
+				Protocol:      fi.String("tcp"),
+				FromPort:      fi.Int64(22),
+				ToPort:        fi.Int64(22),
+			}
+			AddDirectionalGroupRule(c, t)
+		}
+	} else {
+		for _, dest := range bastionGroups {
+			if err := b.addSSHAccessRules(c, "ssh-external-to-bastion"+dest.Suffix, dest.Task); err != nil {
+				return err
+			}
 		}
-		AddDirectionalGroupRule(c, t)
 	}
 
 	// Allow bastion nodes to SSH to masters
@@ -134,47 +229,39 @@ func (b *BastionModelBuilder) Build(c *fi.ModelBuilderContext) error {
 		}
 	}
 
-	// Create security group for bastion ELB
-	{
-		t := &awstasks.SecurityGroup{
-			Name:      fi.String(b.ELBSecurityGroupName(BastionELBSecurityGroupPrefix)),
-			Lifecycle: b.SecurityLifecycle,
+	if lbType != kops.BastionLoadBalancerTypeNone && accessMode != kops.BastionAccessModeSSMSessionManager {
+		// Create security group for bastion ELB
+		{
+			t := &awstasks.SecurityGroup{
+				Name:      fi.String(b.ELBSecurityGroupName(BastionELBSecurityGroupPrefix)),
+				Lifecycle: b.SecurityLifecycle,
 
-			VPC:              b.LinkToVPC(),
-			Description:      fi.String("Security group for bastion ELB"),
-			RemoveExtraRules: []string{"port=22"},
+				VPC:              b.LinkToVPC(),
+				Description:      fi.String("Security group for bastion ELB"),
+				RemoveExtraRules: []string{"port=22"},
+			}
+			t.Tags = b.CloudTags(*t.Name, false)
+			c.AddTask(t)
 		}
-		t.Tags = b.CloudTags(*t.Name, false)
-		c.AddTask(t)
-	}
 
-	// Allow traffic from ELB to egress freely
-	{
-		t := &awstasks.SecurityGroupRule{
-			Name:      fi.String("bastion-elb-egress"),
-			Lifecycle: b.SecurityLifecycle,
-
-			SecurityGroup: b.LinkToELBSecurityGroup(BastionELBSecurityGroupPrefix),
-			Egress:        fi.Bool(true),
-			CIDR:          fi.String("0.0.0.0/0"),
-		}
+		// Allow traffic from ELB to egress freely
+		{
+			t := &awstasks.SecurityGroupRule{
+				Name:      fi.String("bastion-elb-egress"),
+				Lifecycle: b.SecurityLifecycle,
 
-		AddDirectionalGroupRule(c, t)
-	}
+				SecurityGroup: b.LinkToELBSecurityGroup(BastionELBSecurityGroupPrefix),
+				Egress:        fi.Bool(true),
+				CIDR:          fi.String("0.0.0.0/0"),
+			}
 
-	// Allow external access to ELB
-	for _, sshAccess := range b.Cluster.Spec.SSHAccess {
-		t := &awstasks.SecurityGroupRule{
-			Name:      fi.String("ssh-external-to-bastion-elb-" + sshAccess),
-			Lifecycle: b.SecurityLifecycle,
+			AddDirectionalGroupRule(c, t)
+		}
 
-			SecurityGroup: b.LinkToELBSecurityGroup(BastionELBSecurityGroupPrefix),
-			Protocol:      fi.String("tcp"),
-			FromPort:      fi.Int64(22),
-			ToPort:        fi.Int64(22),
-			CIDR:          fi.String(sshAccess),
+		// Allow external access to ELB
+		if err := b.addSSHAccessRules(c, "ssh-external-to-bastion-elb", b.LinkToELBSecurityGroup(BastionELBSecurityGroupPrefix)); err != nil {
+			return err
 		}
-		AddDirectionalGroupRule(c, t)
 	}
 
 	var elbSubnets []*awstasks.Subnet
@@ -199,24 +286,179 @@ func (b *BastionModelBuilder) Build(c *fi.ModelBuilderContext) error {
 		}
 	}
 
-	// Create ELB itself
-	var elb *awstasks.ClassicLoadBalancer
-	{
-		loadBalancerName := b.LBName32("bastion")
+	if accessMode == kops.BastionAccessModeSSMSessionManager {
+		// No ELB, no SSH ingress, no DNSName: the bastion is reached with
+		// `aws ssm start-session`, brokered entirely through these VPC endpoints.
+		//
+		// The SSM agent on the instance also needs an IAM instance profile granting
+		// AmazonSSMManagedInstanceCore to register. No such profile or policy attachment
+		// is created here: the only IAM task type in this tree is IAMRole, which models
+		// service-assumed roles, and there is no instance-profile task or IAM model
+		// builder, so the bastion instance group's IAM role is left unchanged.
+		var bastionSecurityGroups []*awstasks.SecurityGroup
+		for _, src := range bastionGroups {
+			bastionSecurityGroups = append(bastionSecurityGroups, src.Task)
+		}
+
+		// The endpoints are reached over port 443 from the bastion's own security
+		// group; without this rule the bastion can't reach them even once IAM is sorted.
+		for _, dest := range bastionGroups {
+			t := &awstasks.SecurityGroupRule{
+				Name:          fi.String("bastion-to-ssm-endpoints" + dest.Suffix),
+				Lifecycle:     b.SecurityLifecycle,
+				SecurityGroup: dest.Task,
+				SourceGroup:   dest.Task,
+				Protocol:      fi.String("tcp"),
+				FromPort:      fi.Int64(443),
+				ToPort:        fi.Int64(443),
+			}
+			AddDirectionalGroupRule(c, t)
+		}
+
+		for _, service := range bastionSSMVPCEndpointServices {
+			t := &awstasks.VPCEndpoint{
+				Name:            fi.String("bastion-" + service + "." + b.ClusterName()),
+				Lifecycle:       b.Lifecycle,
+				VPC:             b.LinkToVPC(),
+				ServiceName:     fi.String("com.amazonaws." + b.Region + "." + service),
+				VPCEndpointType: fi.String("Interface"),
+				SecurityGroups:  bastionSecurityGroups,
+				Subnets:         elbSubnets,
+				Tags:            b.CloudTags("bastion-"+service+"."+b.ClusterName(), false),
+			}
+			c.AddTask(t)
+		}
+
+		return nil
+	}
+
+	bastionPublicName := ""
+	if b.Cluster.Spec.Topology != nil && b.Cluster.Spec.Topology.Bastion != nil {
+		bastionPublicName = b.Cluster.Spec.Topology.Bastion.BastionPublicName
+	}
+
+	loadBalancerName := b.LBName32("bastion")
 
+	tags := b.CloudTags(loadBalancerName, false)
+	for k, v := range b.Cluster.Spec.CloudLabels {
+		tags[k] = v
+	}
+	// Override the returned name to be the expected LB name
+	tags["Name"] = "bastion." + b.ClusterName()
+
+	var classicELB *awstasks.ClassicLoadBalancer
+
+	switch lbType {
+	case kops.BastionLoadBalancerTypeNetwork:
+		// Create the NLB and its target group itself
+		tg := &awstasks.TargetGroup{
+			Name:               fi.String("bastion-" + b.ClusterName()),
+			Lifecycle:          b.Lifecycle,
+			VPC:                b.LinkToVPC(),
+			Port:               fi.Int64(22),
+			Protocol:           fi.String("TCP"),
+			Tags:               tags,
+			HealthyThreshold:   fi.Int64(2),
+			UnhealthyThreshold: fi.Int64(2),
+		}
+		c.AddTask(tg)
+
+		// Register every bastion ASG's instances with the target group, otherwise the
+		// NLB would have no healthy targets at all.
+		for _, ig := range bastionInstanceGroups {
+			asg := b.LinkToAutoscalingGroup(ig)
+			asg.TargetGroups = append(asg.TargetGroups, tg)
+		}
+
+		nlb := &awstasks.NetworkLoadBalancer{
+			Name:      fi.String("bastion." + b.ClusterName()),
+			Lifecycle: b.Lifecycle,
+
+			LoadBalancerName: fi.String(loadBalancerName),
+			Subnets:          elbSubnets,
+			Listeners: map[string]*awstasks.NetworkLoadBalancerListener{
+				"22": {TargetGroup: tg},
+			},
+			Tags: tags,
+			// NLB traffic keeps the client's source IP, so the bastion-elb SG has to be
+			// attached to the NLB itself for ssh-elb-to-bastion's SourceGroup rule (above)
+			// to ever match; without this, the configured SSH allowlist never reaches the
+			// bastion in Network mode.
+			SecurityGroups: []*awstasks.SecurityGroup{b.LinkToELBSecurityGroup(BastionELBSecurityGroupPrefix)},
+		}
+		c.AddTask(nlb)
+
+		if bastionPublicName != "" {
+			t := &awstasks.DNSName{
+				Name:      fi.String(bastionPublicName),
+				Lifecycle: b.Lifecycle,
+
+				Zone:                      b.LinkToDNSZone(),
+				ResourceType:              fi.String("A"),
+				TargetNetworkLoadBalancer: nlb,
+			}
+			c.AddTask(t)
+		}
+
+	case kops.BastionLoadBalancerTypeNone:
+		// No load balancer: give the bastion a static, allowlist-able address via an EIP
+		// instead. This mode only makes sense with a single bastion instance (MaxSize: 1),
+		// since one EIP can only ever be associated with one running instance at a time.
+		// The EIP is allocated here but not associated with the bastion instance; see
+		// ElasticIP.AssociatedAutoScalingGroup.
+		eip := &awstasks.ElasticIP{
+			Name:      fi.String("bastion." + b.ClusterName()),
+			Lifecycle: b.Lifecycle,
+			Tags:      tags,
+		}
+		if len(bastionInstanceGroups) > 0 {
+			eip.AssociatedAutoScalingGroup = b.LinkToAutoscalingGroup(bastionInstanceGroups[0])
+		}
+		c.AddTask(eip)
+
+		if bastionPublicName != "" {
+			t := &awstasks.DNSName{
+				Name:      fi.String(bastionPublicName),
+				Lifecycle: b.Lifecycle,
+
+				Zone:         b.LinkToDNSZone(),
+				ResourceType: fi.String("A"),
+				TargetIP:     eip.PublicIP,
+			}
+			c.AddTask(t)
+		}
+
+	default:
+		// Create Classic ELB itself
 		idleTimeout := BastionELBDefaultIdleTimeout
 		if b.Cluster.Spec.Topology != nil && b.Cluster.Spec.Topology.Bastion != nil && b.Cluster.Spec.Topology.Bastion.IdleTimeoutSeconds != nil {
 			idleTimeout = time.Second * time.Duration(*b.Cluster.Spec.Topology.Bastion.IdleTimeoutSeconds)
 		}
 
-		tags := b.CloudTags(loadBalancerName, false)
-		for k, v := range b.Cluster.Spec.CloudLabels {
-			tags[k] = v
+		var bastionLB *kops.BastionLoadBalancerSpec
+		if b.Cluster.Spec.Topology != nil && b.Cluster.Spec.Topology.Bastion != nil {
+			bastionLB = b.Cluster.Spec.Topology.Bastion.LoadBalancer
+		}
+
+		// There's usually only a single bastion instance, so without cross-zone balancing
+		// the ELB hashes all traffic to whichever AZ that instance happens to be in,
+		// wasting the multi-AZ subnets we already provisioned for it.
+		crossZoneLoadBalancing := fi.Bool(true)
+		if bastionLB != nil && bastionLB.CrossZoneLoadBalancing != nil {
+			crossZoneLoadBalancing = bastionLB.CrossZoneLoadBalancing
+		}
+
+		var accessLog *awstasks.ClassicLoadBalancerAccessLog
+		if bastionLB != nil && bastionLB.AccessLog != nil {
+			accessLog = &awstasks.ClassicLoadBalancerAccessLog{
+				Enabled:        bastionLB.AccessLog.Enabled,
+				S3BucketName:   bastionLB.AccessLog.S3BucketName,
+				S3BucketPrefix: bastionLB.AccessLog.S3BucketPrefix,
+				EmitInterval:   bastionLB.AccessLog.EmitInterval,
+			}
 		}
-		// Override the returned name to be the expected ELB name
-		tags["Name"] = "bastion." + b.ClusterName()
 
-		elb = &awstasks.ClassicLoadBalancer{
+		elb := &awstasks.ClassicLoadBalancer{
 			Name:      fi.String("bastion." + b.ClusterName()),
 			Lifecycle: b.Lifecycle,
 
@@ -241,6 +483,9 @@ func (b *BastionModelBuilder) Build(c *fi.ModelBuilderContext) error {
 				IdleTimeout: fi.Int64(int64(idleTimeout.Seconds())),
 			},
 
+			CrossZoneLoadBalancing: crossZoneLoadBalancing,
+			AccessLog:              accessLog,
+
 			Tags: tags,
 		}
 		// Add additional security groups to the ELB
@@ -260,25 +505,219 @@ func (b *BastionModelBuilder) Build(c *fi.ModelBuilderContext) error {
 		}
 
 		c.AddTask(elb)
+		classicELB = elb
+
+		if bastionPublicName != "" {
+			// Here we implement the bastion CNAME logic
+			// By default bastions will create a CNAME that follows the `bastion-$clustername` formula
+			t := &awstasks.DNSName{
+				Name:      fi.String(bastionPublicName),
+				Lifecycle: b.Lifecycle,
+
+				Zone:               b.LinkToDNSZone(),
+				ResourceType:       fi.String("A"),
+				TargetLoadBalancer: elb,
+			}
+			c.AddTask(t)
+		}
 	}
 
-	bastionPublicName := ""
-	if b.Cluster.Spec.Topology != nil && b.Cluster.Spec.Topology.Bastion != nil {
-		bastionPublicName = b.Cluster.Spec.Topology.Bastion.BastionPublicName
-	}
-	if bastionPublicName != "" {
-		// Here we implement the bastion CNAME logic
-		// By default bastions will create a CNAME that follows the `bastion-$clustername` formula
-		t := &awstasks.DNSName{
-			Name:      fi.String(bastionPublicName),
+	// Scale the bastion ASG down to 0 after it's sat idle (no ELB traffic) for IdleAfter.
+	// This only applies to the Classic ELB, since CloudWatch's AWS/ELB RequestCount metric
+	// doesn't exist for the NLB or EIP paths.
+	if autoShutdown != nil && autoShutdown.IdleAfter != nil && classicELB != nil {
+		idlePeriod := int64(autoShutdown.IdleAfter.Duration.Seconds())
+
+		var asgNames []string
+		for _, ig := range bastionInstanceGroups {
+			asgNames = append(asgNames, fi.StringValue(b.LinkToAutoscalingGroup(ig).Name))
+		}
+
+		zipFile, err := buildIdleShutdownLambdaZip()
+		if err != nil {
+			return fmt.Errorf("building bastion-idle-shutdown Lambda package: %v", err)
+		}
+
+		scaleDownRole := &awstasks.IAMRole{
+			Name:      fi.String("bastion-idle-shutdown." + b.ClusterName()),
 			Lifecycle: b.Lifecycle,
 
-			Zone:               b.LinkToDNSZone(),
-			ResourceType:       fi.String("A"),
-			TargetLoadBalancer: elb,
+			AssumeRolePolicyDocument: fi.String(lambdaAssumeRolePolicy),
+			// Scoped to the action this function actually performs; the ASG
+			// names themselves aren't usable as a Resource constraint here, since
+			// autoscaling:SetDesiredCapacity is authorized against the ASG ARN,
+			// which isn't known until the ASG is created.
+			InlinePolicyDocument: fi.String(idleShutdownRolePolicy),
+		}
+		c.AddTask(scaleDownRole)
+
+		scaleDownFn := &awstasks.LambdaFunction{
+			Name:         fi.String("bastion-idle-shutdown." + b.ClusterName()),
+			Lifecycle:    b.Lifecycle,
+			FunctionName: fi.String("bastion-idle-shutdown." + b.ClusterName()),
+			Description:  fi.String("Scales the bastion ASG to 0 when CloudWatch reports it has been idle"),
+			Handler:      fi.String("index.handler"),
+			Runtime:      fi.String("python3.9"),
+			Timeout:      fi.Int64(30),
+			ZipFile:      zipFile,
+			Role:         scaleDownRole,
+			Environment: map[string]string{
+				"BASTION_ASG_NAMES": strings.Join(asgNames, ","),
+			},
+		}
+		c.AddTask(scaleDownFn)
+
+		alarm := &awstasks.CloudWatchAlarm{
+			Name:               fi.String("bastion-idle." + b.ClusterName()),
+			Lifecycle:          b.Lifecycle,
+			Namespace:          fi.String("AWS/ELB"),
+			MetricName:         fi.String("RequestCount"),
+			Statistic:          fi.String("Sum"),
+			ComparisonOperator: fi.String("LessThanOrEqualToThreshold"),
+			Threshold:          fi.Float64(0),
+			Period:             fi.Int64(idlePeriod),
+			EvaluationPeriods:  fi.Int64(1),
+			// The ELB publishes no RequestCount data points while it receives no
+			// requests, so missing data is what idleness looks like.
+			TreatMissingData: fi.String("breaching"),
+			Dimensions: map[string]string{
+				"LoadBalancerName": loadBalancerName,
+			},
+			AlarmActions: []*awstasks.LambdaFunction{scaleDownFn},
+		}
+		c.AddTask(alarm)
+	}
+
+	return nil
+}
+
+// lambdaAssumeRolePolicy is the trust policy shared by every Lambda execution role in this
+// series: it lets the Lambda service, and only the Lambda service, assume the role.
+const lambdaAssumeRolePolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"Service": "lambda.amazonaws.com"},
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}`
+
+// idleShutdownRolePolicy authorizes bastion-idle-shutdown to scale any Auto Scaling group
+// down, plus the minimum logging permissions every Lambda function needs to report errors.
+const idleShutdownRolePolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": "autoscaling:SetDesiredCapacity",
+      "Resource": "*"
+    },
+    {
+      "Effect": "Allow",
+      "Action": [
+        "logs:CreateLogGroup",
+        "logs:CreateLogStream",
+        "logs:PutLogEvents"
+      ],
+      "Resource": "arn:aws:logs:*:*:*"
+    }
+  ]
+}`
+
+// idleShutdownLambdaSource is the handler bastion-idle-shutdown runs: it scales every ASG
+// named in the BASTION_ASG_NAMES environment variable to 0. It relies on boto3, which is
+// bundled with every Lambda python3.9 runtime.
+const idleShutdownLambdaSource = `import os
+import boto3
+
+def handler(event, context):
+    client = boto3.client("autoscaling")
+    for name in os.environ["BASTION_ASG_NAMES"].split(","):
+        if name:
+            client.set_desired_capacity(AutoScalingGroupName=name, DesiredCapacity=0, HonorCooldown=False)
+`
+
+// buildIdleShutdownLambdaZip packages idleShutdownLambdaSource as the single-file zip
+// archive Lambda's CreateFunction Code.ZipFile expects, named to match the
+// "index.handler" Handler configured on the LambdaFunction task.
+func buildIdleShutdownLambdaZip() ([]byte, error) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	f, err := w.Create("index.py")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write([]byte(idleShutdownLambdaSource)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("closing zip writer: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// sshAccessSources returns the configured SSH allowlist for the bastion. It prefers
+// Topology.Bastion.SSHAccess, which supports CIDRs, prefix lists, and security group
+// references; Cluster.Spec.SSHAccess is kept as a CIDR-only fallback for clusters that
+// haven't migrated to the richer field.
+func (b *BastionModelBuilder) sshAccessSources() []kops.SSHAccessSource {
+	if b.Cluster.Spec.Topology != nil && b.Cluster.Spec.Topology.Bastion != nil && len(b.Cluster.Spec.Topology.Bastion.SSHAccess) > 0 {
+		return b.Cluster.Spec.Topology.Bastion.SSHAccess
+	}
+
+	var sources []kops.SSHAccessSource
+	for _, cidr := range b.Cluster.Spec.SSHAccess {
+		sources = append(sources, kops.SSHAccessSource{CIDR: cidr})
+	}
+	return sources
+}
+
+// addSSHAccessRules adds one SecurityGroupRule per configured SSH source, granting it
+// port 22 access to target. namePrefix keeps naming deterministic and unique per target.
+func (b *BastionModelBuilder) addSSHAccessRules(c *fi.ModelBuilderContext, namePrefix string, target *awstasks.SecurityGroup) error {
+	for _, source := range b.sshAccessSources() {
+		t := &awstasks.SecurityGroupRule{
+			Lifecycle:     b.SecurityLifecycle,
+			SecurityGroup: target,
+			Protocol:      fi.String("tcp"),
+			FromPort:      fi.Int64(22),
+			ToPort:        fi.Int64(22),
+		}
+
+		switch {
+		case source.SecurityGroupID != "":
+			sg := &awstasks.SecurityGroup{
+				Name:      fi.String(source.SecurityGroupID),
+				Lifecycle: b.SecurityLifecycle,
+				ID:        fi.String(source.SecurityGroupID),
+				Shared:    fi.Bool(true),
+			}
+			if err := c.EnsureTask(sg); err != nil {
+				return err
+			}
+			t.Name = fi.String(namePrefix + "-sg-" + source.SecurityGroupID)
+			t.SourceGroup = sg
+
+		case source.PrefixListID != "":
+			t.Name = fi.String(namePrefix + "-pl-" + source.PrefixListID)
+			t.SourcePrefixListId = fi.String(source.PrefixListID)
+
+		default:
+			t.Name = fi.String(namePrefix + "-" + source.CIDR)
+			host := strings.SplitN(source.CIDR, "/", 2)[0]
+			if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+				t.IPv6CIDR = fi.String(source.CIDR)
+			} else {
+				t.CIDR = fi.String(source.CIDR)
+			}
 		}
-		c.AddTask(t)
 
+		AddDirectionalGroupRule(c, t)
 	}
+
 	return nil
 }
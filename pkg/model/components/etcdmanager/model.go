@@ -19,10 +19,13 @@ package etcdmanager
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
 	"k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/assets"
@@ -45,6 +48,59 @@ import (
 	"k8s.io/kops/util/pkg/exec"
 )
 
+// EtcdClusterProfile describes the port/CA allocation for one named etcd cluster (e.g.
+// "main", "events", "cilium"). CNIs and operators that need a dedicated etcd cluster
+// register their own profile with RegisterEtcdClusterProfile instead of patching a
+// switch statement here.
+type EtcdClusterProfile struct {
+	ClientPort            int
+	PeerPort              int
+	GRPCPort              int
+	QuarantinedClientPort int
+	MetricsPort           int
+	// ManagerMetricsPort defaults to MetricsPort+10 when zero.
+	ManagerMetricsPort int
+	// ExtraClientCANames are additional client CAs created alongside etcd-clients-ca,
+	// e.g. "etcd-clients-ca-cilium" for the cilium profile.
+	ExtraClientCANames []string
+}
+
+var etcdClusterProfiles = map[string]EtcdClusterProfile{
+	"main": {
+		ClientPort:            4001,
+		PeerPort:              2380,
+		GRPCPort:              wellknownports.EtcdMainGRPC,
+		QuarantinedClientPort: wellknownports.EtcdMainQuarantinedClientPort,
+		MetricsPort:           8081,
+	},
+	"events": {
+		ClientPort:            4002,
+		PeerPort:              2381,
+		GRPCPort:              wellknownports.EtcdEventsGRPC,
+		QuarantinedClientPort: wellknownports.EtcdEventsQuarantinedClientPort,
+		MetricsPort:           8082,
+	},
+	"cilium": {
+		ClientPort:            4003,
+		PeerPort:              2382,
+		GRPCPort:              wellknownports.EtcdCiliumGRPC,
+		QuarantinedClientPort: wellknownports.EtcdCiliumQuarantinedClientPort,
+		MetricsPort:           8083,
+		ExtraClientCANames:    []string{"etcd-clients-ca-cilium"},
+	},
+}
+
+// RegisterEtcdClusterProfile registers the port/CA allocation for an etcd cluster name
+// that isn't one of the built-in "main"/"events"/"cilium" clusters, so that a CNI or an
+// operator (e.g. a dedicated etcd for Calico, kube-router, or aenix-io/etcd-operator) can
+// declare one without patching kOps itself. GRPCPort and QuarantinedClientPort should be
+// chosen from the wellknownports.EtcdUserProfile* ranges, which are reserved so a
+// third-party profile can't collide with the built-in clusters' ports. It is not safe to
+// call concurrently with EtcdManagerBuilder.Build.
+func RegisterEtcdClusterProfile(name string, profile EtcdClusterProfile) {
+	etcdClusterProfiles[name] = profile
+}
+
 // EtcdManagerBuilder builds the manifest for the etcd-manager
 type EtcdManagerBuilder struct {
 	*model.KopsModelContext
@@ -72,23 +128,24 @@ func (b *EtcdManagerBuilder) Build(c *fi.ModelBuilderContext) error {
 			return fmt.Errorf("backupStore must be set for use with etcd-manager")
 		}
 
-		manifest, err := b.buildManifest(etcdCluster)
-		if err != nil {
-			return err
-		}
+		csiVolumes := etcdCluster.Manager != nil && etcdCluster.Manager.VolumeMode == kops.EtcdManagerVolumeModeCSI
 
-		manifestYAML, err := k8scodecs.ToVersionedYaml(manifest)
-		if err != nil {
-			return fmt.Errorf("error marshaling manifest to yaml: %v", err)
+		if csiVolumes {
+			// CSI mode provisions one PersistentVolumeClaim per member (see
+			// buildVolumeClaimTasks), so it also needs one pod manifest per member:
+			// a single shared manifest can only ever bind one member's RWO claim.
+			for i := range etcdCluster.Members {
+				member := etcdCluster.Members[i]
+				if err := b.addManifestTask(c, etcdCluster, &member); err != nil {
+					return err
+				}
+			}
+		} else {
+			if err := b.addManifestTask(c, etcdCluster, nil); err != nil {
+				return err
+			}
 		}
 
-		c.AddTask(&fitasks.ManagedFile{
-			Contents:  fi.NewBytesResource(manifestYAML),
-			Lifecycle: b.Lifecycle,
-			Location:  fi.String("manifests/etcd/" + name + ".yaml"),
-			Name:      fi.String("manifests-etcdmanager-" + name),
-		})
-
 		info := &etcdClusterSpec{
 			EtcdVersion: version,
 			MemberCount: int32(len(etcdCluster.Members)),
@@ -116,41 +173,268 @@ func (b *EtcdManagerBuilder) Build(c *fi.ModelBuilderContext) error {
 		})
 
 		// We create a CA keypair to enable secure communication
-		c.AddTask(&fitasks.Keypair{
-			Name:      fi.String("etcd-manager-ca-" + etcdCluster.Name),
-			Lifecycle: b.Lifecycle,
-			Subject:   "cn=etcd-manager-ca-" + etcdCluster.Name,
-			Type:      "ca",
-		})
+		if err := b.addCATask(c, "etcd-manager-ca-"+etcdCluster.Name, false); err != nil {
+			return err
+		}
 
 		// We create a CA for etcd peers and a separate one for clients
-		c.AddTask(&fitasks.Keypair{
-			Name:      fi.String("etcd-peers-ca-" + etcdCluster.Name),
-			Lifecycle: b.Lifecycle,
-			Subject:   "cn=etcd-peers-ca-" + etcdCluster.Name,
-			Type:      "ca",
-		})
+		if err := b.addCATask(c, "etcd-peers-ca-"+etcdCluster.Name, false); err != nil {
+			return err
+		}
 
 		// Because API server can only have a single client-cert, we need to share a client CA
-		if err := c.EnsureTask(&fitasks.Keypair{
-			Name:      fi.String("etcd-clients-ca"),
-			Lifecycle: b.Lifecycle,
-			Subject:   "cn=etcd-clients-ca",
-			Type:      "ca",
-		}); err != nil {
+		if err := b.addCATask(c, "etcd-clients-ca", true); err != nil {
 			return err
 		}
 
-		if etcdCluster.Name == "cilium" {
-			c.AddTask(&fitasks.Keypair{
-				Name:      fi.String("etcd-clients-ca-cilium"),
-				Lifecycle: b.Lifecycle,
-				Subject:   "cn=etcd-clients-ca-cilium",
-				Type:      "ca",
-			})
+		profile, ok := etcdClusterProfiles[etcdClusterProfileName(etcdCluster)]
+		if !ok {
+			return fmt.Errorf("unknown etcd cluster key %q", etcdCluster.Name)
+		}
+		for _, caName := range profile.ExtraClientCANames {
+			if err := b.addCATask(c, caName, false); err != nil {
+				return err
+			}
+		}
+
+		if err := b.buildMetricsTasks(c, etcdCluster, profile); err != nil {
+			return err
 		}
+
+		if csiVolumes {
+			if err := b.buildVolumeClaimTasks(c, etcdCluster); err != nil {
+				return err
+			}
+			if err := b.buildPeerDiscoveryService(c, etcdCluster, profile); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// addManifestTask builds the etcd-manager pod manifest and writes it as a ManagedFile.
+// member is nil outside of CSI mode, where a single manifest is shared by every master;
+// in CSI mode member identifies which etcd member (and therefore which member-specific
+// PersistentVolumeClaim, see buildVolumeClaimTasks) this particular manifest is for, and
+// each member gets its own file so its claim name can be templated in.
+func (b *EtcdManagerBuilder) addManifestTask(c *fi.ModelBuilderContext, etcdCluster kops.EtcdClusterSpec, member *kops.EtcdMemberSpec) error {
+	manifest, err := b.buildManifest(etcdCluster, member)
+	if err != nil {
+		return err
+	}
+
+	manifestYAML, err := k8scodecs.ToVersionedYaml(manifest)
+	if err != nil {
+		return fmt.Errorf("error marshaling manifest to yaml: %v", err)
+	}
+
+	suffix := ""
+	if member != nil {
+		suffix = "-" + member.Name
+	}
+
+	c.AddTask(&fitasks.ManagedFile{
+		Contents:  fi.NewBytesResource(manifestYAML),
+		Lifecycle: b.Lifecycle,
+		Location:  fi.String("manifests/etcd/" + etcdCluster.Name + suffix + ".yaml"),
+		Name:      fi.String("manifests-etcdmanager-" + etcdCluster.Name + suffix),
+	})
+
+	return nil
+}
+
+// buildVolumeClaimTasks pre-provisions one PersistentVolumeClaim per etcd member, named
+// from etcdVolumeNameTag, for clusters that opt into VolumeMode: CSI instead of letting
+// etcd-manager attach cloud block devices itself. A claim is scoped to a single member
+// (AccessModes: ReadWriteOnce) because a real cluster runs one etcd-manager pod per
+// master, each with its own manifest (see addManifestTask) binding its own claim; sharing
+// one RWO claim across members would only ever let one master's pod mount it.
+func (b *EtcdManagerBuilder) buildVolumeClaimTasks(c *fi.ModelBuilderContext, etcdCluster kops.EtcdClusterSpec) error {
+	storageSize := resource.MustParse("20Gi")
+
+	for i := range etcdCluster.Members {
+		member := etcdCluster.Members[i]
+
+		pvc := &v1.PersistentVolumeClaim{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "PersistentVolumeClaim",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      etcdVolumeNameTag(etcdCluster, member),
+				Namespace: "kube-system",
+			},
+			Spec: v1.PersistentVolumeClaimSpec{
+				AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceStorage: storageSize,
+					},
+				},
+			},
+		}
+
+		pvcYAML, err := k8scodecs.ToVersionedYaml(pvc)
+		if err != nil {
+			return fmt.Errorf("error marshaling etcd PersistentVolumeClaim to yaml: %v", err)
+		}
+
+		c.AddTask(&fitasks.ManagedFile{
+			Contents:  fi.NewBytesResource(pvcYAML),
+			Lifecycle: b.Lifecycle,
+			Location:  fi.String("manifests/etcd/" + etcdCluster.Name + "-" + member.Name + "-pvc.yaml"),
+			Name:      fi.String("manifests-etcdmanager-pvc-" + etcdCluster.Name + "-" + member.Name),
+		})
+	}
+
+	return nil
+}
+
+// buildPeerDiscoveryService emits the headless Service that stands in for the DNS-suffix and
+// cloud-volume-tag discovery etcd-manager normally relies on: in CSI mode both of those are
+// disabled (see buildPod), so without this Service member pods would have no way to find
+// each other via the __name__ placeholders in PeerUrls/ClientUrls.
+func (b *EtcdManagerBuilder) buildPeerDiscoveryService(c *fi.ModelBuilderContext, etcdCluster kops.EtcdClusterSpec, profile EtcdClusterProfile) error {
+	name := "etcd-manager-" + etcdCluster.Name
+
+	svc := &v1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "kube-system",
+			Labels: map[string]string{
+				"k8s-app": name,
+			},
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: "None",
+			Selector: map[string]string{
+				"k8s-app": name,
+			},
+			Ports: []v1.ServicePort{
+				{Name: "etcd-client", Port: int32(profile.ClientPort)},
+				{Name: "etcd-peer", Port: int32(profile.PeerPort)},
+				{Name: "etcd-grpc", Port: int32(profile.GRPCPort)},
+			},
+		},
+	}
+
+	svcYAML, err := k8scodecs.ToVersionedYaml(svc)
+	if err != nil {
+		return fmt.Errorf("error marshaling etcd peer-discovery service to yaml: %v", err)
+	}
+
+	c.AddTask(&fitasks.ManagedFile{
+		Contents:  fi.NewBytesResource(svcYAML),
+		Lifecycle: b.Lifecycle,
+		Location:  fi.String("manifests/etcd/" + etcdCluster.Name + "-discovery.yaml"),
+		Name:      fi.String("manifests-etcdmanager-discovery-" + etcdCluster.Name),
+	})
+
+	return nil
+}
+
+// etcdClusterProfileName returns the registry key for an etcd cluster: its explicit
+// Profile override if set, otherwise its Name (which matches the built-in profiles).
+func etcdClusterProfileName(etcdCluster kops.EtcdClusterSpec) string {
+	if etcdCluster.Profile != "" {
+		return etcdCluster.Profile
+	}
+	return etcdCluster.Name
+}
+
+// buildMetricsTasks emits the headless Service (and, if Prometheus monitoring is enabled on
+// the cluster, a matching ServiceMonitor) needed for kube-prometheus-stack to scrape etcd and
+// etcd-manager, so users don't have to hand-roll this manifest themselves.
+func (b *EtcdManagerBuilder) buildMetricsTasks(c *fi.ModelBuilderContext, etcdCluster kops.EtcdClusterSpec, profile EtcdClusterProfile) error {
+	name := "etcd-manager-" + etcdCluster.Name
+
+	// Dedicated metrics listeners, kept off the main client/peer/grpc ports allocated in buildPod.
+	etcdMetricsPort := int32(profile.MetricsPort)
+	managerMetricsPort := int32(profile.ManagerMetricsPort)
+	if managerMetricsPort == 0 {
+		managerMetricsPort = etcdMetricsPort + 10
+	}
+
+	svc := &v1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + "-metrics",
+			Namespace: "kube-system",
+			Labels: map[string]string{
+				"k8s-app": name,
+			},
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: "None",
+			Selector: map[string]string{
+				"k8s-app": name,
+			},
+			Ports: []v1.ServicePort{
+				{Name: "metrics", Port: etcdMetricsPort},
+				{Name: "manager-metrics", Port: managerMetricsPort},
+			},
+		},
+	}
+
+	svcYAML, err := k8scodecs.ToVersionedYaml(svc)
+	if err != nil {
+		return fmt.Errorf("error marshaling etcd metrics service to yaml: %v", err)
+	}
+
+	contents := svcYAML
+
+	if b.Cluster.Spec.Monitoring != nil && b.Cluster.Spec.Monitoring.Prometheus != nil && b.Cluster.Spec.Monitoring.Prometheus.Enabled {
+		serviceMonitorYAML := fmt.Sprintf(`---
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  name: %s
+  namespace: kube-system
+  labels:
+    k8s-app: %s
+spec:
+  selector:
+    matchLabels:
+      k8s-app: %s
+  endpoints:
+  - port: metrics
+  - port: manager-metrics
+`, name, name, name)
+		contents = append(contents, []byte(serviceMonitorYAML)...)
 	}
 
+	c.AddTask(&fitasks.ManagedFile{
+		Contents:  fi.NewBytesResource(contents),
+		Lifecycle: b.Lifecycle,
+		Location:  fi.String("manifests/etcd/" + etcdCluster.Name + "-metrics.yaml"),
+		Name:      fi.String("manifests-etcdmanager-metrics-" + etcdCluster.Name),
+	})
+
+	return nil
+}
+
+// addCATask adds the Keypair task for one of etcd-manager's CA slots.
+func (b *EtcdManagerBuilder) addCATask(c *fi.ModelBuilderContext, name string, shared bool) error {
+	task := &fitasks.Keypair{
+		Name:      fi.String(name),
+		Lifecycle: b.Lifecycle,
+		Subject:   "cn=" + name,
+		Type:      "ca",
+	}
+
+	if shared {
+		return c.EnsureTask(task)
+	}
+	c.AddTask(task)
 	return nil
 }
 
@@ -159,8 +443,8 @@ type etcdClusterSpec struct {
 	EtcdVersion string `json:"etcdVersion,omitempty"`
 }
 
-func (b *EtcdManagerBuilder) buildManifest(etcdCluster kops.EtcdClusterSpec) (*v1.Pod, error) {
-	return b.buildPod(etcdCluster)
+func (b *EtcdManagerBuilder) buildManifest(etcdCluster kops.EtcdClusterSpec, member *kops.EtcdMemberSpec) (*v1.Pod, error) {
+	return b.buildPod(etcdCluster, member)
 }
 
 // Until we introduce the bundle, we hard-code the manifest
@@ -206,8 +490,10 @@ spec:
     name: pki
 `
 
-// buildPod creates the pod spec, based on the EtcdClusterSpec
-func (b *EtcdManagerBuilder) buildPod(etcdCluster kops.EtcdClusterSpec) (*v1.Pod, error) {
+// buildPod creates the pod spec, based on the EtcdClusterSpec. member is non-nil only in
+// CSI mode, where it selects which per-member PersistentVolumeClaim (see
+// buildVolumeClaimTasks) this particular pod manifest should mount.
+func (b *EtcdManagerBuilder) buildPod(etcdCluster kops.EtcdClusterSpec, member *kops.EtcdMemberSpec) (*v1.Pod, error) {
 	var pod *v1.Pod
 	var container *v1.Container
 
@@ -313,24 +599,24 @@ func (b *EtcdManagerBuilder) buildPod(etcdCluster kops.EtcdClusterSpec) (*v1.Pod
 		dnsInternalSuffix = ".internal." + b.Cluster.ObjectMeta.Name
 	}
 
-	switch etcdCluster.Name {
-	case "main":
-		clusterName = "etcd"
-
-	case "events":
-		clientPort = 4002
-		peerPort = 2381
-		grpcPort = wellknownports.EtcdEventsGRPC
-		quarantinedClientPort = wellknownports.EtcdEventsQuarantinedClientPort
-	case "cilium":
-		clientPort = 4003
-		peerPort = 2382
-		grpcPort = wellknownports.EtcdCiliumGRPC
-		quarantinedClientPort = wellknownports.EtcdCiliumQuarantinedClientPort
-	default:
+	profile, ok := etcdClusterProfiles[etcdClusterProfileName(etcdCluster)]
+	if !ok {
 		return nil, fmt.Errorf("unknown etcd cluster key %q", etcdCluster.Name)
 	}
 
+	if etcdCluster.Name == "main" {
+		clusterName = "etcd"
+	}
+	clientPort = profile.ClientPort
+	peerPort = profile.PeerPort
+	grpcPort = profile.GRPCPort
+	quarantinedClientPort = profile.QuarantinedClientPort
+	metricsPort := profile.MetricsPort
+	managerMetricsPort := profile.ManagerMetricsPort
+	if managerMetricsPort == 0 {
+		managerMetricsPort = metricsPort + 10
+	}
+
 	if backupStore == "" {
 		return nil, fmt.Errorf("backupStore must be set for use with etcd-manager")
 	}
@@ -342,14 +628,22 @@ func (b *EtcdManagerBuilder) buildPod(etcdCluster kops.EtcdClusterSpec) (*v1.Pod
 	}
 	logFile := "/var/log/" + name + ".log"
 
+	csiVolumes := etcdCluster.Manager != nil && etcdCluster.Manager.VolumeMode == kops.EtcdManagerVolumeModeCSI
+
 	config := &config{
-		Containerized: true,
+		// Containerized/VolumeProvider/VolumeTag/VolumeNameTag tell etcd-manager to attach
+		// cloud block devices itself; when a CSI driver already provisions the disk as a
+		// PersistentVolumeClaim, none of that applies, and DNS for members is handled by a
+		// normal headless Service instead of etcd-manager's own DNS-suffix/gossip logic.
+		Containerized: !csiVolumes,
 		ClusterName:   clusterName,
 		BackupStore:   backupStore,
 		GrpcPort:      grpcPort,
-		DNSSuffix:     dnsInternalSuffix,
 		EtcdInsecure:  etcdInsecure,
 	}
+	if !csiVolumes {
+		config.DNSSuffix = dnsInternalSuffix
+	}
 
 	config.LogLevel = 6
 
@@ -362,26 +656,58 @@ func (b *EtcdManagerBuilder) buildPod(etcdCluster kops.EtcdClusterSpec) (*v1.Pod
 		config.DiscoveryPollInterval = etcdCluster.Manager.DiscoveryPollInterval
 	}
 
+	var extraBackupDestinations []string
+	if etcdCluster.Backups != nil {
+		if etcdCluster.Backups.FullInterval != nil {
+			config.BackupInterval = fi.String(etcdCluster.Backups.FullInterval.Duration.String())
+		}
+		if etcdCluster.Backups.Retention != nil && etcdCluster.Backups.Retention.Count != nil {
+			config.BackupRetentionCount = etcdCluster.Backups.Retention.Count
+		}
+		// etcd-manager only understands a single --backup-store; any further
+		// destinations are fanned out to by the backup-sidecar container below.
+		extraBackupDestinations = etcdCluster.Backups.Destinations
+	}
+
 	{
 		scheme := "https"
 
 		config.PeerUrls = fmt.Sprintf("%s://__name__:%d", scheme, peerPort)
 		config.ClientUrls = fmt.Sprintf("%s://%s:%d", scheme, clientHost, clientPort)
 		config.QuarantineClientUrls = fmt.Sprintf("%s://__name__:%d", scheme, quarantinedClientPort)
+		config.MetricsUrls = fmt.Sprintf("http://0.0.0.0:%d", metricsPort)
+		// Distinct from MetricsUrls (etcd's own metrics): this is where
+		// etcd-manager serves its own process metrics, which is the port the
+		// "manager-metrics" Service port and ServiceMonitor target actually scrape.
+		config.ManagerMetricsListen = fmt.Sprintf("http://0.0.0.0:%d", managerMetricsPort)
+	}
 
-		// TODO: We need to wire these into the etcd-manager spec
-		// // add timeout/heartbeat settings
-		if etcdCluster.LeaderElectionTimeout != nil {
-			//      envs = append(envs, v1.EnvVar{Name: "ETCD_ELECTION_TIMEOUT", Value: convEtcdSettingsToMs(etcdClusterSpec.LeaderElectionTimeout)})
-			return nil, fmt.Errorf("LeaderElectionTimeout not supported by etcd-manager")
-		}
-		if etcdCluster.HeartbeatInterval != nil {
-			//      envs = append(envs, v1.EnvVar{Name: "ETCD_HEARTBEAT_INTERVAL", Value: convEtcdSettingsToMs(etcdClusterSpec.HeartbeatInterval)})
-			return nil, fmt.Errorf("HeartbeatInterval not supported by etcd-manager")
-		}
+	// Tuning knobs that etcd itself understands only as ETCD_* environment
+	// variables are forwarded the same way etcdCluster.Manager.Env is.
+	var etcdTuningEnv []v1.EnvVar
+	if etcdCluster.LeaderElectionTimeout != nil {
+		etcdTuningEnv = append(etcdTuningEnv, v1.EnvVar{Name: "ETCD_ELECTION_TIMEOUT", Value: convEtcdSettingsToMs(etcdCluster.LeaderElectionTimeout)})
+	}
+	if etcdCluster.HeartbeatInterval != nil {
+		etcdTuningEnv = append(etcdTuningEnv, v1.EnvVar{Name: "ETCD_HEARTBEAT_INTERVAL", Value: convEtcdSettingsToMs(etcdCluster.HeartbeatInterval)})
+	}
+	if etcdCluster.SnapshotCount != nil {
+		etcdTuningEnv = append(etcdTuningEnv, v1.EnvVar{Name: "ETCD_SNAPSHOT_COUNT", Value: strconv.FormatInt(*etcdCluster.SnapshotCount, 10)})
+	}
+	if etcdCluster.QuotaBackendBytes != nil {
+		etcdTuningEnv = append(etcdTuningEnv, v1.EnvVar{Name: "ETCD_QUOTA_BACKEND_BYTES", Value: strconv.FormatInt(*etcdCluster.QuotaBackendBytes, 10)})
+	}
+	if etcdCluster.MaxRequestBytes != nil {
+		etcdTuningEnv = append(etcdTuningEnv, v1.EnvVar{Name: "ETCD_MAX_REQUEST_BYTES", Value: strconv.FormatInt(*etcdCluster.MaxRequestBytes, 10)})
+	}
+	if etcdCluster.AutoCompactionMode != nil {
+		etcdTuningEnv = append(etcdTuningEnv, v1.EnvVar{Name: "ETCD_AUTO_COMPACTION_MODE", Value: *etcdCluster.AutoCompactionMode})
+	}
+	if etcdCluster.AutoCompactionRetention != nil {
+		etcdTuningEnv = append(etcdTuningEnv, v1.EnvVar{Name: "ETCD_AUTO_COMPACTION_RETENTION", Value: *etcdCluster.AutoCompactionRetention})
 	}
 
-	{
+	if !csiVolumes {
 		switch kops.CloudProviderID(b.Cluster.Spec.CloudProvider) {
 		case kops.CloudProviderAWS:
 			config.VolumeProvider = "aws"
@@ -516,6 +842,8 @@ func (b *EtcdManagerBuilder) buildPod(etcdCluster kops.EtcdClusterSpec) (*v1.Pod
 
 	container.Env = envMap.ToEnvVars()
 
+	container.Env = append(container.Env, etcdTuningEnv...)
+
 	if etcdCluster.Manager != nil && len(etcdCluster.Manager.Env) > 0 {
 		for _, envVar := range etcdCluster.Manager.Env {
 			klog.Warningf("overloading ENV var in manifest %s with %s=%s", bundle, envVar.Name, envVar.Value)
@@ -546,12 +874,221 @@ func (b *EtcdManagerBuilder) buildPod(etcdCluster kops.EtcdClusterSpec) (*v1.Pod
 		}
 	}
 
+	// etcd-manager only ever writes to a single --backup-store; extra destinations are
+	// fanned out to by a sidecar that snapshots every IncrementalInterval (or FullInterval,
+	// if that is unset) and prunes by Retention.MaxAge.
+	if len(extraBackupDestinations) > 0 {
+		backupSidecar, err := buildBackupSidecar(etcdCluster, clientHost, clientPort, etcdInsecure, extraBackupDestinations)
+		if err != nil {
+			return nil, err
+		}
+		pod.Spec.Containers = append(pod.Spec.Containers, backupSidecar)
+	}
+
+	if csiVolumes {
+		if member == nil {
+			return nil, fmt.Errorf("member must be set when building a manifest for VolumeMode: CSI")
+		}
+		pod.Name = pod.Name + "-" + member.Name
+		rewritePodForCSIVolumes(pod, container, etcdCluster, *member)
+	}
+
 	kubemanifest.MarkPodAsCritical(pod)
 	kubemanifest.MarkPodAsClusterCritical(pod)
 
 	return pod, nil
 }
 
+// uploadCommandForDestination returns the shell pipeline that copies
+// localPath to dest, dispatching on dest's scheme since etcdctl's own
+// snapshot save has no notion of a destination beyond the local disk.
+func uploadCommandForDestination(dest, localPath string) string {
+	switch {
+	case strings.HasPrefix(dest, "s3://"):
+		return fmt.Sprintf(`aws s3 cp %q "%s/$(date -u +%%Y%%m%%dT%%H%%M%%S)/etcd.db"`, localPath, dest)
+	case strings.HasPrefix(dest, "gs://"):
+		return fmt.Sprintf(`gsutil cp %q "%s/$(date -u +%%Y%%m%%dT%%H%%M%%S)/etcd.db"`, localPath, dest)
+	default:
+		// Anything else is treated as a local/NFS-mounted path.
+		return fmt.Sprintf(`mkdir -p "%s/$(date -u +%%Y%%m%%dT%%H%%M%%S)" && cp %q "%s/$(date -u +%%Y%%m%%dT%%H%%M%%S)/etcd.db"`, dest, localPath, dest)
+	}
+}
+
+// pruneCommandForDestination deletes snapshot directories under dest older
+// than maxAge (to the minute, and at least one minute), dispatching on dest's scheme
+// the same way upload does. Snapshot directories are named by
+// uploadCommandForDestination as a UTC timestamp (YYYYMMDDTHHMMSS), so for s3:// and
+// gs:// destinations age is computed by comparing that name against a cutoff rather
+// than relying on any object-modified-time metadata; local paths are pruned by the
+// directories' modification time.
+func pruneCommandForDestination(dest string, maxAge time.Duration) string {
+	maxAgeMinutes := int(maxAge.Minutes())
+	if maxAgeMinutes < 1 {
+		maxAgeMinutes = 1
+	}
+	switch {
+	case strings.HasPrefix(dest, "s3://"):
+		return fmt.Sprintf(`cutoff=$(date -u -d "%d minutes ago" +%%Y%%m%%dT%%H%%M%%S); aws s3 ls %q | awk '{print $NF}' | sed 's#/$##' | while read -r d; do [ -n "$d" ] && [ "$d" \< "$cutoff" ] && aws s3 rm --recursive "%s/${d}"; done`, maxAgeMinutes, dest, dest)
+	case strings.HasPrefix(dest, "gs://"):
+		return fmt.Sprintf(`cutoff=$(date -u -d "%d minutes ago" +%%Y%%m%%dT%%H%%M%%S); gsutil ls %q | while read -r d; do b=$(basename "${d%%/}"); [ -n "$b" ] && [ "$b" \< "$cutoff" ] && gsutil -m rm -r "${d}"; done`, maxAgeMinutes, dest)
+	default:
+		return fmt.Sprintf(`find %q -mindepth 1 -maxdepth 1 -type d -mmin +%d -exec rm -rf {} +`, dest, maxAgeMinutes)
+	}
+}
+
+// etcdManagerPKIMountPath is where the backup sidecar mounts the same "pki" hostPath volume
+// the etcd-manager container itself uses; etcd-manager bootstraps its client-facing TLS
+// material for the etcd-clients-ca CA underneath it, in a directory named after the CA.
+const etcdManagerPKIMountPath = "/etc/kubernetes/pki/etcd-manager"
+
+// buildBackupSidecar returns a container that runs etcdctl snapshot save every
+// IncrementalInterval, or every FullInterval (etcd-manager's own backup interval) if
+// IncrementalInterval is unset; each save is a full snapshot. It then uploads (and, per Encryption, encrypts) the snapshot to
+// any destinations beyond the primary --backup-store, which etcd-manager itself has no notion
+// of, and prunes snapshots older than Retention.MaxAge from those destinations.
+func buildBackupSidecar(etcdCluster kops.EtcdClusterSpec, clientHost string, clientPort int, etcdInsecure bool, destinations []string) (v1.Container, error) {
+	fullInterval := 1 * time.Hour
+	if etcdCluster.Backups != nil && etcdCluster.Backups.FullInterval != nil {
+		fullInterval = etcdCluster.Backups.FullInterval.Duration
+	}
+	snapshotInterval := fullInterval
+	if etcdCluster.Backups != nil && etcdCluster.Backups.IncrementalInterval != nil {
+		snapshotInterval = etcdCluster.Backups.IncrementalInterval.Duration
+	}
+
+	snapshotPath := "/tmp/etcd-snapshot.db"
+
+	var env []v1.EnvVar
+	env = append(env, v1.EnvVar{Name: "ETCDCTL_API", Value: "3"})
+
+	var uploadSteps []string
+	for _, dest := range destinations {
+		localPath := snapshotPath
+		if enc := etcdCluster.Backups.Encryption; enc != nil {
+			encryptedPath := snapshotPath + ".enc"
+			switch enc.Mode {
+			case kops.EtcdBackupEncryptionModeKMS:
+				uploadSteps = append(uploadSteps, fmt.Sprintf(`aws kms encrypt --key-id %q --plaintext fileb://%s --output text --query CiphertextBlob | base64 -d > %q`, enc.KMSKeyID, snapshotPath, encryptedPath))
+			default:
+				if enc.PassphraseSecretName == "" {
+					return v1.Container{}, fmt.Errorf("etcd backup encryption mode %q for cluster %q requires PassphraseSecretName to be set", enc.Mode, etcdCluster.Name)
+				}
+				env = append(env, v1.EnvVar{
+					Name: "ETCD_BACKUP_PASSPHRASE",
+					ValueFrom: &v1.EnvVarSource{
+						SecretKeyRef: &v1.SecretKeySelector{
+							LocalObjectReference: v1.LocalObjectReference{Name: enc.PassphraseSecretName},
+							Key:                  "passphrase",
+						},
+					},
+				})
+				uploadSteps = append(uploadSteps, fmt.Sprintf(`openssl enc -aes-256-gcm -salt -pbkdf2 -pass env:ETCD_BACKUP_PASSPHRASE -in %q -out %q`, snapshotPath, encryptedPath))
+			}
+			localPath = encryptedPath
+		}
+		uploadSteps = append(uploadSteps, uploadCommandForDestination(dest, localPath)+" || true")
+	}
+
+	var pruneSteps []string
+	if etcdCluster.Backups != nil && etcdCluster.Backups.Retention != nil && etcdCluster.Backups.Retention.MaxAge != nil {
+		maxAge := etcdCluster.Backups.Retention.MaxAge.Duration
+		for _, dest := range destinations {
+			pruneSteps = append(pruneSteps, pruneCommandForDestination(dest, maxAge)+" || true")
+		}
+	}
+
+	tlsFlags := ""
+	if !etcdInsecure {
+		caDir := etcdManagerPKIMountPath + "/etcd-clients-ca"
+		tlsFlags = fmt.Sprintf(" --cacert=%s/ca.crt --cert=%s/client.crt --key=%s/client.key", caDir, caDir, caDir)
+	}
+
+	// sleep only accepts a plain number of seconds (optionally with a single unit
+	// suffix), not Go's "1h0m0s" Duration.String() format, so the interval is
+	// converted to whole seconds here rather than interpolated directly.
+	sleepSeconds := strconv.Itoa(int(snapshotInterval.Seconds()))
+
+	// A failed snapshot save is logged and skipped rather than masked with "|| true", so
+	// Destinations fan-out failures are visible in the container's logs instead of spinning
+	// forever in silence; the loop itself keeps retrying on the next tick.
+	script := fmt.Sprintf(
+		`while true; do
+  if etcdctl --endpoints=https://%s:%d%s snapshot save %s; then
+    %s
+    %s
+  else
+    echo "backup-controller: etcdctl snapshot save failed, skipping this cycle" >&2
+  fi
+  sleep %s
+done`, clientHost, clientPort, tlsFlags, snapshotPath, strings.Join(uploadSteps, "\n    "), strings.Join(pruneSteps, "\n    "), sleepSeconds)
+
+	return v1.Container{
+		Name:    "backup-controller",
+		Image:   "kopeio/etcd-manager:3.0.20210228",
+		Command: []string{"/bin/sh", "-c", script},
+		Env:     env,
+		VolumeMounts: []v1.VolumeMount{
+			{MountPath: etcdManagerPKIMountPath, Name: "pki"},
+		},
+	}, nil
+}
+
+// rewritePodForCSIVolumes drops the host-level privileges that etcd-manager normally needs
+// to attach cloud block devices itself, and instead mounts the data dir from the
+// PersistentVolumeClaim provisioned for this member out-of-band (see
+// buildVolumeClaimTasks), for clusters where the cloud provider already exposes disks via
+// a CSI driver.
+func rewritePodForCSIVolumes(pod *v1.Pod, container *v1.Container, etcdCluster kops.EtcdClusterSpec, member kops.EtcdMemberSpec) {
+	pod.Spec.HostPID = false
+	pod.Spec.HostNetwork = false
+
+	var keptVolumes []v1.Volume
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == "rootfs" || v.Name == "run" {
+			continue
+		}
+		keptVolumes = append(keptVolumes, v)
+	}
+	pod.Spec.Volumes = keptVolumes
+
+	var keptMounts []v1.VolumeMount
+	for _, m := range container.VolumeMounts {
+		if m.Name == "rootfs" || m.Name == "run" {
+			continue
+		}
+		keptMounts = append(keptMounts, m)
+	}
+	container.VolumeMounts = keptMounts
+
+	container.SecurityContext = nil
+
+	claimName := etcdVolumeNameTag(etcdCluster, member)
+	pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+		Name: "etcd-data",
+		VolumeSource: v1.VolumeSource{
+			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+				ClaimName: claimName,
+			},
+		},
+	})
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+		Name:      "etcd-data",
+		MountPath: "/var/etcd/" + etcdCluster.Name,
+	})
+}
+
+// etcdVolumeNameTag is the name shared by a member's PersistentVolumeClaim fitask and the
+// volume that mounts it into that member's etcd-manager pod.
+func etcdVolumeNameTag(etcdCluster kops.EtcdClusterSpec, member kops.EtcdMemberSpec) string {
+	return "etcd-" + etcdCluster.Name + "-" + member.Name + "-data"
+}
+
+// convEtcdSettingsToMs converts a *metav1.Duration into the millisecond string
+// that etcd's ETCD_ELECTION_TIMEOUT / ETCD_HEARTBEAT_INTERVAL env vars expect.
+func convEtcdSettingsToMs(dur *metav1.Duration) string {
+	return strconv.FormatInt(dur.Duration.Milliseconds(), 10)
+}
+
 // config defines the flags for etcd-manager
 type config struct {
 	// LogLevel sets the log verbosity level
@@ -575,8 +1112,12 @@ type config struct {
 	ClientUrls            string   `flag:"client-urls"`
 	DiscoveryPollInterval *string  `flag:"discovery-poll-interval"`
 	QuarantineClientUrls  string   `flag:"quarantine-client-urls"`
+	MetricsUrls           string   `flag:"listen-metrics-urls"`
+	ManagerMetricsListen  string   `flag:"manager-metrics-listen"`
 	ClusterName           string   `flag:"cluster-name"`
 	BackupStore           string   `flag:"backup-store"`
+	BackupInterval        *string  `flag:"backup-interval"`
+	BackupRetentionCount  *int32   `flag:"backup-retention-count"`
 	DataDir               string   `flag:"data-dir"`
 	VolumeProvider        string   `flag:"volume-provider"`
 	VolumeTag             []string `flag:"volume-tag,repeat"`
@@ -0,0 +1,41 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package wellknownports centralizes the fixed ports kOps-managed components
+// listen on, so that two components never collide by accident.
+package wellknownports
+
+const (
+	EtcdMainGRPC                      = 3996
+	EtcdMainQuarantinedClientPort     = 3997
+	EtcdEventsGRPC                    = 3991
+	EtcdEventsQuarantinedClientPort   = 3992
+	EtcdCiliumGRPC                    = 3986
+	EtcdCiliumQuarantinedClientPort   = 3987
+
+	// EtcdUserProfileGRPCRangeStart and EtcdUserProfileGRPCRangeEnd bound the
+	// GRPC ports available to third-party etcd cluster profiles registered
+	// with etcdmanager.RegisterEtcdClusterProfile, kept clear of the
+	// built-in main/events/cilium ports above.
+	EtcdUserProfileGRPCRangeStart = 3950
+	EtcdUserProfileGRPCRangeEnd   = 3985
+
+	// EtcdUserProfileQuarantinedClientPortRangeStart and …RangeEnd bound the
+	// quarantined client ports available to third-party etcd cluster
+	// profiles, for the same reason.
+	EtcdUserProfileQuarantinedClientPortRangeStart = 3910
+	EtcdUserProfileQuarantinedClientPortRangeEnd   = 3949
+)
@@ -0,0 +1,195 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// AutoScalingGroup is a minimal reference to one of the cluster's instance
+// group ASGs, as linked via AWSModelContext.LinkToAutoscalingGroup. The ASG
+// itself is created and sized by the instance group model; this task only
+// reconciles the parts of it this series owns: its ELBv2 target group
+// registrations.
+type AutoScalingGroup struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+	ID        *string
+
+	// TargetGroups registers the ASG's instances with one or more ELBv2 target
+	// groups, e.g. so an NLB in front of the bastion actually has healthy targets.
+	TargetGroups []*TargetGroup
+}
+
+var _ fi.CloudupTask = &AutoScalingGroup{}
+
+func (e *AutoScalingGroup) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+func (e *AutoScalingGroup) Find(c *fi.CloudupContext) (*AutoScalingGroup, error) {
+	cloud := c.T.Cloud.(awsup.AWSCloud)
+
+	response, err := cloud.Autoscaling().DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{e.Name},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing AutoScalingGroups: %v", err)
+	}
+	if response == nil || len(response.AutoScalingGroups) == 0 {
+		return nil, nil
+	}
+
+	asg := response.AutoScalingGroups[0]
+	actual := &AutoScalingGroup{
+		Name:      e.Name,
+		Lifecycle: e.Lifecycle,
+		ID:        asg.AutoScalingGroupName,
+	}
+	for _, arn := range asg.TargetGroupARNs {
+		actual.TargetGroups = append(actual.TargetGroups, &TargetGroup{ARN: arn})
+	}
+
+	return actual, nil
+}
+
+func (e *AutoScalingGroup) CheckChanges(a, ex, changes *AutoScalingGroup) error {
+	return nil
+}
+
+func (_ *AutoScalingGroup) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *AutoScalingGroup) error {
+	if changes.TargetGroups == nil {
+		return nil
+	}
+
+	existing := make(map[string]bool)
+	if a != nil {
+		for _, tg := range a.TargetGroups {
+			existing[aws.StringValue(tg.ARN)] = true
+		}
+	}
+
+	var toAttach []*string
+	wanted := make(map[string]bool)
+	for _, tg := range e.TargetGroups {
+		wanted[aws.StringValue(tg.ARN)] = true
+		if !existing[aws.StringValue(tg.ARN)] {
+			toAttach = append(toAttach, tg.ARN)
+		}
+	}
+	if len(toAttach) > 0 {
+		_, err := t.Cloud.Autoscaling().AttachLoadBalancerTargetGroups(&autoscaling.AttachLoadBalancerTargetGroupsInput{
+			AutoScalingGroupName: e.Name,
+			TargetGroupARNs:      toAttach,
+		})
+		if err != nil {
+			return fmt.Errorf("error attaching target groups to AutoScalingGroup %q: %v", aws.StringValue(e.Name), err)
+		}
+	}
+
+	var toDetach []*string
+	if a != nil {
+		for _, tg := range a.TargetGroups {
+			if !wanted[aws.StringValue(tg.ARN)] {
+				toDetach = append(toDetach, tg.ARN)
+			}
+		}
+	}
+	if len(toDetach) > 0 {
+		_, err := t.Cloud.Autoscaling().DetachLoadBalancerTargetGroups(&autoscaling.DetachLoadBalancerTargetGroupsInput{
+			AutoScalingGroupName: e.Name,
+			TargetGroupARNs:      toDetach,
+		})
+		if err != nil {
+			return fmt.Errorf("error detaching target groups from AutoScalingGroup %q: %v", aws.StringValue(e.Name), err)
+		}
+	}
+
+	return nil
+}
+
+// AutoScalingScheduledAction manages a recurring resize of an Auto Scaling
+// group, e.g. to scale a bastion ASG down overnight and back up in the
+// morning.
+type AutoScalingScheduledAction struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+
+	AutoscalingGroup *AutoScalingGroup
+	DesiredCapacity  *int64
+	MinSize          *int64
+	MaxSize          *int64
+	// Recurrence is a cron expression, evaluated in UTC.
+	Recurrence *string
+}
+
+var _ fi.CloudupTask = &AutoScalingScheduledAction{}
+
+func (e *AutoScalingScheduledAction) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+func (e *AutoScalingScheduledAction) Find(c *fi.CloudupContext) (*AutoScalingScheduledAction, error) {
+	cloud := c.T.Cloud.(awsup.AWSCloud)
+
+	response, err := cloud.Autoscaling().DescribeScheduledActions(&autoscaling.DescribeScheduledActionsInput{
+		AutoScalingGroupName: e.AutoscalingGroup.Name,
+		ScheduledActionNames: []*string{e.Name},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing AutoScalingScheduledActions: %v", err)
+	}
+	if response == nil || len(response.ScheduledUpdateGroupActions) == 0 {
+		return nil, nil
+	}
+
+	action := response.ScheduledUpdateGroupActions[0]
+	return &AutoScalingScheduledAction{
+		Name:             e.Name,
+		Lifecycle:        e.Lifecycle,
+		AutoscalingGroup: e.AutoscalingGroup,
+		DesiredCapacity:  action.DesiredCapacity,
+		MinSize:          action.MinSize,
+		MaxSize:          action.MaxSize,
+		Recurrence:       action.Recurrence,
+	}, nil
+}
+
+func (e *AutoScalingScheduledAction) CheckChanges(a, ex, changes *AutoScalingScheduledAction) error {
+	return nil
+}
+
+func (_ *AutoScalingScheduledAction) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *AutoScalingScheduledAction) error {
+	_, err := t.Cloud.Autoscaling().PutScheduledUpdateGroupAction(&autoscaling.PutScheduledUpdateGroupActionInput{
+		AutoScalingGroupName: e.AutoscalingGroup.Name,
+		ScheduledActionName:  e.Name,
+		DesiredCapacity:      e.DesiredCapacity,
+		MinSize:              e.MinSize,
+		MaxSize:              e.MaxSize,
+		Recurrence:           e.Recurrence,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating/updating AutoScalingScheduledAction %q: %v", aws.StringValue(e.Name), err)
+	}
+	return nil
+}
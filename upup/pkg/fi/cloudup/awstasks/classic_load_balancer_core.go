@@ -0,0 +1,347 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elb"
+
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// SecurityGroup manages an EC2 security group.
+type SecurityGroup struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+
+	ID               *string
+	VPC              *VPC
+	Description      *string
+	RemoveExtraRules []string
+	Shared           *bool
+	Tags             map[string]string
+}
+
+var _ fi.CloudupTask = &SecurityGroup{}
+
+func (e *SecurityGroup) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+// SecurityGroupRule manages a single ingress/egress rule on a SecurityGroup.
+type SecurityGroupRule struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+
+	SecurityGroup *SecurityGroup
+	SourceGroup   *SecurityGroup
+	CIDR          *string
+	IPv6CIDR      *string
+	// SourcePrefixListId scopes the rule to an AWS-managed or customer-managed
+	// prefix list, e.g. the EC2 Instance Connect service prefix list.
+	SourcePrefixListId *string
+	Protocol           *string
+	FromPort           *int64
+	ToPort             *int64
+	Egress             *bool
+}
+
+var _ fi.CloudupTask = &SecurityGroupRule{}
+
+func (e *SecurityGroupRule) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+// VPC is a minimal reference to the cluster's VPC, as linked via AWSModelContext.LinkToVPC.
+type VPC struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+	ID        *string
+	Shared    *bool
+}
+
+var _ fi.CloudupTask = &VPC{}
+
+func (e *VPC) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+// Subnet is a reference to one of the cluster's subnets.
+type Subnet struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+	ID        *string
+	Zone      *string
+	VPC       *VPC
+}
+
+var _ fi.CloudupTask = &Subnet{}
+
+func (e *Subnet) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+// ClassicLoadBalancerListener maps one ELB listener port to an instance port.
+type ClassicLoadBalancerListener struct {
+	InstancePort int64
+}
+
+// ClassicLoadBalancerHealthCheck configures the ELB's instance health check.
+type ClassicLoadBalancerHealthCheck struct {
+	Target             *string
+	Timeout            *int64
+	Interval           *int64
+	HealthyThreshold   *int64
+	UnhealthyThreshold *int64
+}
+
+// ClassicLoadBalancerConnectionSettings configures connection-level ELB behavior.
+type ClassicLoadBalancerConnectionSettings struct {
+	IdleTimeout *int64
+}
+
+// ClassicLoadBalancerAccessLog configures access logging on a ClassicLoadBalancer.
+type ClassicLoadBalancerAccessLog struct {
+	Enabled        *bool
+	S3BucketName   string
+	S3BucketPrefix string
+	EmitInterval   *int64
+}
+
+// ClassicLoadBalancer manages an EC2-Classic ("v1") Elastic Load Balancer.
+type ClassicLoadBalancer struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+
+	LoadBalancerName   *string
+	SecurityGroups     []*SecurityGroup
+	Subnets            []*Subnet
+	Listeners          map[string]*ClassicLoadBalancerListener
+	HealthCheck        *ClassicLoadBalancerHealthCheck
+	ConnectionSettings *ClassicLoadBalancerConnectionSettings
+
+	// CrossZoneLoadBalancing spreads traffic across instances in every
+	// attached zone rather than hashing each connection to a single zone.
+	CrossZoneLoadBalancing *bool
+	// AccessLog configures access logging for this load balancer.
+	AccessLog *ClassicLoadBalancerAccessLog
+
+	Tags map[string]string
+}
+
+var _ fi.CloudupTask = &ClassicLoadBalancer{}
+
+func (e *ClassicLoadBalancer) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+func (e *ClassicLoadBalancer) Find(c *fi.CloudupContext) (*ClassicLoadBalancer, error) {
+	cloud := c.T.Cloud.(awsup.AWSCloud)
+
+	response, err := cloud.ELB().DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+		LoadBalancerNames: []*string{e.LoadBalancerName},
+	})
+	if err != nil {
+		if awsup.AWSErrorCode(err) == elb.ErrCodeAccessPointNotFoundException {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting ClassicLoadBalancer %q: %v", aws.StringValue(e.LoadBalancerName), err)
+	}
+	if response == nil || len(response.LoadBalancerDescriptions) == 0 {
+		return nil, nil
+	}
+
+	actual := &ClassicLoadBalancer{
+		Name:             e.Name,
+		Lifecycle:        e.Lifecycle,
+		LoadBalancerName: e.LoadBalancerName,
+	}
+
+	attrResponse, err := cloud.ELB().DescribeLoadBalancerAttributes(&elb.DescribeLoadBalancerAttributesInput{
+		LoadBalancerName: e.LoadBalancerName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting attributes for ClassicLoadBalancer %q: %v", aws.StringValue(e.LoadBalancerName), err)
+	}
+	if attrResponse != nil && attrResponse.LoadBalancerAttributes != nil {
+		attrs := attrResponse.LoadBalancerAttributes
+		if attrs.CrossZoneLoadBalancing != nil {
+			actual.CrossZoneLoadBalancing = attrs.CrossZoneLoadBalancing.Enabled
+		}
+		if attrs.AccessLog != nil {
+			actual.AccessLog = &ClassicLoadBalancerAccessLog{
+				Enabled:        attrs.AccessLog.Enabled,
+				S3BucketName:   aws.StringValue(attrs.AccessLog.S3BucketName),
+				S3BucketPrefix: aws.StringValue(attrs.AccessLog.S3BucketPrefix),
+				EmitInterval:   attrs.AccessLog.EmitInterval,
+			}
+		}
+	}
+
+	return actual, nil
+}
+
+func (e *ClassicLoadBalancer) CheckChanges(a, ex, changes *ClassicLoadBalancer) error {
+	if a != nil {
+		if changes.LoadBalancerName != nil {
+			return fmt.Errorf("LoadBalancerName cannot be changed on a ClassicLoadBalancer after creation")
+		}
+	}
+	return nil
+}
+
+func (_ *ClassicLoadBalancer) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *ClassicLoadBalancer) error {
+	if a == nil {
+		var sgIDs []*string
+		for _, sg := range e.SecurityGroups {
+			sgIDs = append(sgIDs, sg.ID)
+		}
+		var subnetIDs []*string
+		for _, s := range e.Subnets {
+			subnetIDs = append(subnetIDs, s.ID)
+		}
+		var listeners []*elb.Listener
+		for loadBalancerPort, l := range e.Listeners {
+			port, err := parseListenerPort(loadBalancerPort)
+			if err != nil {
+				return err
+			}
+			listeners = append(listeners, &elb.Listener{
+				Protocol:         aws.String("TCP"),
+				LoadBalancerPort: aws.Int64(port),
+				InstanceProtocol: aws.String("TCP"),
+				InstancePort:     aws.Int64(l.InstancePort),
+			})
+		}
+
+		_, err := t.Cloud.ELB().CreateLoadBalancer(&elb.CreateLoadBalancerInput{
+			LoadBalancerName: e.LoadBalancerName,
+			SecurityGroups:   sgIDs,
+			Subnets:          subnetIDs,
+			Listeners:        listeners,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating ClassicLoadBalancer %q: %v", aws.StringValue(e.LoadBalancerName), err)
+		}
+
+		tags := t.Cloud.BuildTags(e.Name)
+		for k, v := range e.Tags {
+			tags[k] = v
+		}
+		if err := t.AddELBTags(aws.StringValue(e.LoadBalancerName), tags); err != nil {
+			return fmt.Errorf("error tagging ClassicLoadBalancer %q: %v", aws.StringValue(e.LoadBalancerName), err)
+		}
+
+		if e.HealthCheck != nil {
+			_, err := t.Cloud.ELB().ConfigureHealthCheck(&elb.ConfigureHealthCheckInput{
+				LoadBalancerName: e.LoadBalancerName,
+				HealthCheck: &elb.HealthCheck{
+					Target:             e.HealthCheck.Target,
+					Timeout:            e.HealthCheck.Timeout,
+					Interval:           e.HealthCheck.Interval,
+					HealthyThreshold:   e.HealthCheck.HealthyThreshold,
+					UnhealthyThreshold: e.HealthCheck.UnhealthyThreshold,
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("error configuring health check for ClassicLoadBalancer %q: %v", aws.StringValue(e.LoadBalancerName), err)
+			}
+		}
+	}
+
+	attrs := &elb.LoadBalancerAttributes{}
+	changed := false
+	if e.ConnectionSettings != nil {
+		attrs.ConnectionSettings = &elb.ConnectionSettings{IdleTimeout: e.ConnectionSettings.IdleTimeout}
+		changed = true
+	}
+	if e.CrossZoneLoadBalancing != nil {
+		attrs.CrossZoneLoadBalancing = &elb.CrossZoneLoadBalancing{Enabled: e.CrossZoneLoadBalancing}
+		changed = true
+	}
+	if e.AccessLog != nil {
+		attrs.AccessLog = &elb.AccessLog{
+			Enabled:        e.AccessLog.Enabled,
+			S3BucketName:   aws.String(e.AccessLog.S3BucketName),
+			S3BucketPrefix: aws.String(e.AccessLog.S3BucketPrefix),
+			EmitInterval:   e.AccessLog.EmitInterval,
+		}
+		changed = true
+	}
+	if changed {
+		_, err := t.Cloud.ELB().ModifyLoadBalancerAttributes(&elb.ModifyLoadBalancerAttributesInput{
+			LoadBalancerName:       e.LoadBalancerName,
+			LoadBalancerAttributes: attrs,
+		})
+		if err != nil {
+			return fmt.Errorf("error setting attributes on ClassicLoadBalancer %q: %v", aws.StringValue(e.LoadBalancerName), err)
+		}
+	}
+
+	return nil
+}
+
+// parseListenerPort parses the map key of ClassicLoadBalancer.Listeners, which is the
+// load balancer port as a string (e.g. "22"), into the int64 ELB's API expects.
+func parseListenerPort(loadBalancerPort string) (int64, error) {
+	var port int64
+	if _, err := fmt.Sscanf(loadBalancerPort, "%d", &port); err != nil {
+		return 0, fmt.Errorf("invalid ClassicLoadBalancer listener port %q: %v", loadBalancerPort, err)
+	}
+	return port, nil
+}
+
+// DNSName manages a Route53 record pointing at one of the cluster's load balancers or a
+// static IP.
+type DNSName struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+
+	Zone               *DNSZone
+	ResourceType       *string
+	TargetLoadBalancer *ClassicLoadBalancer
+	// TargetNetworkLoadBalancer is set instead of TargetLoadBalancer when the
+	// bastion (or another consumer) is fronted by an NLB rather than a
+	// Classic ELB.
+	TargetNetworkLoadBalancer *NetworkLoadBalancer
+	// TargetIP is set instead of TargetLoadBalancer/TargetNetworkLoadBalancer
+	// for an A record pointing directly at a static address, e.g. a bastion's
+	// Elastic IP when it has no load balancer at all.
+	TargetIP *string
+}
+
+var _ fi.CloudupTask = &DNSName{}
+
+func (e *DNSName) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+// DNSZone is a reference to the cluster's hosted zone.
+type DNSZone struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+	ID        *string
+}
+
+var _ fi.CloudupTask = &DNSZone{}
+
+func (e *DNSZone) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// CloudWatchAlarm manages a CloudWatch metric alarm, e.g. one that fires a
+// LambdaFunction when a bastion's Classic ELB has been idle for a while.
+type CloudWatchAlarm struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+
+	Namespace          *string
+	MetricName         *string
+	Statistic          *string
+	ComparisonOperator *string
+	Threshold          *float64
+	Period             *int64
+	EvaluationPeriods  *int64
+	TreatMissingData   *string
+	Dimensions         map[string]string
+
+	AlarmActions []*LambdaFunction
+}
+
+var _ fi.CloudupTask = &CloudWatchAlarm{}
+
+func (e *CloudWatchAlarm) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+func (e *CloudWatchAlarm) Find(c *fi.CloudupContext) (*CloudWatchAlarm, error) {
+	cloud := c.T.Cloud.(awsup.AWSCloud)
+
+	response, err := cloud.CloudWatch().DescribeAlarms(&cloudwatch.DescribeAlarmsInput{
+		AlarmNames: []*string{e.Name},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing CloudWatchAlarms: %v", err)
+	}
+	if response == nil || len(response.MetricAlarms) == 0 {
+		return nil, nil
+	}
+
+	alarm := response.MetricAlarms[0]
+	actual := &CloudWatchAlarm{
+		Name:               e.Name,
+		Lifecycle:          e.Lifecycle,
+		Namespace:          alarm.Namespace,
+		MetricName:         alarm.MetricName,
+		Statistic:          alarm.Statistic,
+		ComparisonOperator: alarm.ComparisonOperator,
+		Threshold:          alarm.Threshold,
+		Period:             alarm.Period,
+		EvaluationPeriods:  alarm.EvaluationPeriods,
+		TreatMissingData:   alarm.TreatMissingData,
+		Dimensions:         make(map[string]string),
+	}
+	for _, d := range alarm.Dimensions {
+		actual.Dimensions[aws.StringValue(d.Name)] = aws.StringValue(d.Value)
+	}
+	for _, actionARN := range alarm.AlarmActions {
+		actual.AlarmActions = append(actual.AlarmActions, &LambdaFunction{ARN: actionARN})
+	}
+
+	return actual, nil
+}
+
+func (e *CloudWatchAlarm) CheckChanges(a, ex, changes *CloudWatchAlarm) error {
+	return nil
+}
+
+func (_ *CloudWatchAlarm) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *CloudWatchAlarm) error {
+	var dimensions []*cloudwatch.Dimension
+	for k, v := range e.Dimensions {
+		dimensions = append(dimensions, &cloudwatch.Dimension{Name: aws.String(k), Value: aws.String(v)})
+	}
+	var actions []*string
+	for _, fn := range e.AlarmActions {
+		actions = append(actions, fn.ARN)
+	}
+
+	_, err := t.Cloud.CloudWatch().PutMetricAlarm(&cloudwatch.PutMetricAlarmInput{
+		AlarmName:          e.Name,
+		Namespace:          e.Namespace,
+		MetricName:         e.MetricName,
+		Statistic:          e.Statistic,
+		ComparisonOperator: e.ComparisonOperator,
+		Threshold:          e.Threshold,
+		Period:             e.Period,
+		EvaluationPeriods:  e.EvaluationPeriods,
+		TreatMissingData:   e.TreatMissingData,
+		Dimensions:         dimensions,
+		AlarmActions:       actions,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating/updating CloudWatchAlarm %q: %v", aws.StringValue(e.Name), err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// ElasticIP manages a VPC Elastic IP, used to give the bastion a stable,
+// allowlist-able address when it has no load balancer in front of it.
+type ElasticIP struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+
+	Tags map[string]string
+
+	PublicIP *string
+
+	// AssociatedAutoScalingGroup is the ASG this EIP should be associated with. The
+	// allocation itself is handled by RenderAWS; the instance actually calling
+	// ec2:AssociateAddress against itself still needs to happen from that instance's
+	// own boot-time logic, since this tree has no bastion launch-template/user-data
+	// builder to own that code.
+	AssociatedAutoScalingGroup *AutoScalingGroup
+}
+
+var _ fi.CloudupTask = &ElasticIP{}
+
+func (e *ElasticIP) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+func (e *ElasticIP) Find(c *fi.CloudupContext) (*ElasticIP, error) {
+	cloud := c.T.Cloud.(awsup.AWSCloud)
+
+	if e.PublicIP == nil {
+		return nil, nil
+	}
+
+	response, err := cloud.EC2().DescribeAddresses(&ec2.DescribeAddressesInput{
+		PublicIps: []*string{e.PublicIP},
+	})
+	if err != nil {
+		if awsup.AWSErrorCode(err) == "InvalidAddress.NotFound" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error listing ElasticIPs: %v", err)
+	}
+	if response == nil || len(response.Addresses) == 0 {
+		return nil, nil
+	}
+
+	address := response.Addresses[0]
+	actual := &ElasticIP{
+		Name:                       e.Name,
+		Lifecycle:                  e.Lifecycle,
+		PublicIP:                   address.PublicIp,
+		AssociatedAutoScalingGroup: e.AssociatedAutoScalingGroup,
+		Tags:                       make(map[string]string),
+	}
+	for _, tag := range address.Tags {
+		actual.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return actual, nil
+}
+
+func (e *ElasticIP) CheckChanges(a, ex, changes *ElasticIP) error {
+	if a != nil {
+		if changes.PublicIP != nil {
+			return fmt.Errorf("PublicIP cannot be changed after an ElasticIP is created")
+		}
+	}
+	return nil
+}
+
+func (_ *ElasticIP) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *ElasticIP) error {
+	if a == nil {
+		response, err := t.Cloud.EC2().AllocateAddress(&ec2.AllocateAddressInput{
+			Domain: aws.String(ec2.DomainTypeVpc),
+		})
+		if err != nil {
+			return fmt.Errorf("error allocating ElasticIP: %v", err)
+		}
+		e.PublicIP = response.PublicIp
+
+		tags := t.Cloud.BuildTags(e.Name)
+		for k, v := range e.Tags {
+			tags[k] = v
+		}
+		if err := t.AddAWSTags(aws.StringValue(response.AllocationId), tags); err != nil {
+			return fmt.Errorf("error tagging ElasticIP %q: %v", aws.StringValue(e.PublicIP), err)
+		}
+	}
+
+	// The address is not associated with any instance here: nothing in this tree calls
+	// ec2:AssociateAddress for the instance the ASG is currently running, so the EIP
+	// stays unattached.
+
+	return nil
+}
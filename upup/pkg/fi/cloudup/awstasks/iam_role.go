@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// IAMRole manages a single-purpose IAM role assumed by an AWS service (as opposed to an
+// EC2 instance profile, which this series has no model builder for). Today its only
+// consumer is LambdaFunction.Role.
+type IAMRole struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+
+	// AssumeRolePolicyDocument is the trust policy, e.g. allowing
+	// lambda.amazonaws.com to assume this role.
+	AssumeRolePolicyDocument *string
+	// InlinePolicyDocument is attached to the role under a policy name matching
+	// the role's own name.
+	InlinePolicyDocument *string
+
+	ARN *string
+}
+
+var _ fi.CloudupTask = &IAMRole{}
+
+func (e *IAMRole) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+func (e *IAMRole) Find(c *fi.CloudupContext) (*IAMRole, error) {
+	cloud := c.T.Cloud.(awsup.AWSCloud)
+
+	response, err := cloud.IAM().GetRole(&iam.GetRoleInput{
+		RoleName: e.Name,
+	})
+	if err != nil {
+		if awsup.AWSErrorCode(err) == iam.ErrCodeNoSuchEntityException {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting IAMRole %q: %v", aws.StringValue(e.Name), err)
+	}
+	if response == nil || response.Role == nil {
+		return nil, nil
+	}
+
+	actual := &IAMRole{
+		Name:                     e.Name,
+		Lifecycle:                e.Lifecycle,
+		ARN:                      response.Role.Arn,
+		AssumeRolePolicyDocument: response.Role.AssumeRolePolicyDocument,
+	}
+
+	policyResponse, err := cloud.IAM().GetRolePolicy(&iam.GetRolePolicyInput{
+		RoleName:   e.Name,
+		PolicyName: e.Name,
+	})
+	if err == nil && policyResponse != nil {
+		actual.InlinePolicyDocument = policyResponse.PolicyDocument
+	} else if err != nil && awsup.AWSErrorCode(err) != iam.ErrCodeNoSuchEntityException {
+		return nil, fmt.Errorf("error getting inline policy for IAMRole %q: %v", aws.StringValue(e.Name), err)
+	}
+
+	return actual, nil
+}
+
+func (e *IAMRole) CheckChanges(a, ex, changes *IAMRole) error {
+	return nil
+}
+
+func (_ *IAMRole) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *IAMRole) error {
+	if a == nil {
+		response, err := t.Cloud.IAM().CreateRole(&iam.CreateRoleInput{
+			RoleName:                 e.Name,
+			AssumeRolePolicyDocument: e.AssumeRolePolicyDocument,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating IAMRole %q: %v", aws.StringValue(e.Name), err)
+		}
+		e.ARN = response.Role.Arn
+	} else if changes.AssumeRolePolicyDocument != nil {
+		_, err := t.Cloud.IAM().UpdateAssumeRolePolicy(&iam.UpdateAssumeRolePolicyInput{
+			RoleName:       e.Name,
+			PolicyDocument: e.AssumeRolePolicyDocument,
+		})
+		if err != nil {
+			return fmt.Errorf("error updating trust policy for IAMRole %q: %v", aws.StringValue(e.Name), err)
+		}
+	}
+
+	if e.InlinePolicyDocument != nil && (a == nil || changes.InlinePolicyDocument != nil) {
+		_, err := t.Cloud.IAM().PutRolePolicy(&iam.PutRolePolicyInput{
+			RoleName:       e.Name,
+			PolicyName:     e.Name,
+			PolicyDocument: e.InlinePolicyDocument,
+		})
+		if err != nil {
+			return fmt.Errorf("error putting inline policy for IAMRole %q: %v", aws.StringValue(e.Name), err)
+		}
+	}
+
+	return nil
+}
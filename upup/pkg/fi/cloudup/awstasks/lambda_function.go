@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// LambdaFunction manages a Lambda function, e.g. the one that scales a
+// bastion ASG to 0 when a CloudWatchAlarm fires on idleness.
+type LambdaFunction struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+
+	FunctionName *string
+	Description  *string
+	Handler      *string
+	Runtime      *string
+	Timeout      *int64
+
+	// Role is the IAM role the function assumes when invoked; its ARN is passed to Lambda.
+	Role *IAMRole
+
+	// ZipFile is the function's deployment package, inlined directly instead of
+	// uploaded to S3 first - matching Lambda's CreateFunction Code.ZipFile field.
+	ZipFile []byte
+
+	// Environment is exposed to the function's code as environment variables,
+	// e.g. which Auto Scaling group(s) bastion-idle-shutdown should act on.
+	Environment map[string]string
+
+	ARN *string
+}
+
+var _ fi.CloudupTask = &LambdaFunction{}
+
+func (e *LambdaFunction) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+func (e *LambdaFunction) Find(c *fi.CloudupContext) (*LambdaFunction, error) {
+	cloud := c.T.Cloud.(awsup.AWSCloud)
+
+	response, err := cloud.Lambda().GetFunction(&lambda.GetFunctionInput{
+		FunctionName: e.FunctionName,
+	})
+	if err != nil {
+		if awsup.AWSErrorCode(err) == lambda.ErrCodeResourceNotFoundException {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting LambdaFunction %q: %v", aws.StringValue(e.FunctionName), err)
+	}
+	if response == nil || response.Configuration == nil {
+		return nil, nil
+	}
+
+	config := response.Configuration
+	env := make(map[string]string)
+	if config.Environment != nil {
+		for k, v := range config.Environment.Variables {
+			env[k] = aws.StringValue(v)
+		}
+	}
+
+	return &LambdaFunction{
+		Name:         e.Name,
+		Lifecycle:    e.Lifecycle,
+		FunctionName: config.FunctionName,
+		Description:  config.Description,
+		Handler:      config.Handler,
+		Runtime:      config.Runtime,
+		Timeout:      config.Timeout,
+		Role:         &IAMRole{ARN: config.Role},
+		Environment:  env,
+		ARN:          config.FunctionArn,
+	}, nil
+}
+
+func (e *LambdaFunction) CheckChanges(a, ex, changes *LambdaFunction) error {
+	if a != nil {
+		if changes.FunctionName != nil {
+			return fmt.Errorf("FunctionName cannot be changed on a LambdaFunction after creation")
+		}
+	}
+	return nil
+}
+
+func (_ *LambdaFunction) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *LambdaFunction) error {
+	var env *lambda.Environment
+	if len(e.Environment) > 0 {
+		vars := make(map[string]*string)
+		for k, v := range e.Environment {
+			vars[k] = aws.String(v)
+		}
+		env = &lambda.Environment{Variables: vars}
+	}
+
+	if a == nil {
+		response, err := t.Cloud.Lambda().CreateFunction(&lambda.CreateFunctionInput{
+			FunctionName: e.FunctionName,
+			Description:  e.Description,
+			Handler:      e.Handler,
+			Runtime:      e.Runtime,
+			Timeout:      e.Timeout,
+			Role:         e.Role.ARN,
+			Code:         &lambda.FunctionCode{ZipFile: e.ZipFile},
+			Environment:  env,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating LambdaFunction %q: %v", aws.StringValue(e.FunctionName), err)
+		}
+		e.ARN = response.FunctionArn
+		return nil
+	}
+
+	if changes.ZipFile != nil && !bytes.Equal(a.ZipFile, e.ZipFile) {
+		_, err := t.Cloud.Lambda().UpdateFunctionCode(&lambda.UpdateFunctionCodeInput{
+			FunctionName: e.FunctionName,
+			ZipFile:      e.ZipFile,
+		})
+		if err != nil {
+			return fmt.Errorf("error updating LambdaFunction %q code: %v", aws.StringValue(e.FunctionName), err)
+		}
+	}
+
+	if changes.Environment != nil || changes.Timeout != nil || changes.Description != nil {
+		_, err := t.Cloud.Lambda().UpdateFunctionConfiguration(&lambda.UpdateFunctionConfigurationInput{
+			FunctionName: e.FunctionName,
+			Description:  e.Description,
+			Timeout:      e.Timeout,
+			Role:         e.Role.ARN,
+			Environment:  env,
+		})
+		if err != nil {
+			return fmt.Errorf("error updating LambdaFunction %q configuration: %v", aws.StringValue(e.FunctionName), err)
+		}
+	}
+
+	return nil
+}
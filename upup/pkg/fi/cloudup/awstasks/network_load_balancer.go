@@ -0,0 +1,278 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// TargetGroup manages an ELBv2 target group, the unit an NLB/ALB listener
+// forwards traffic to.
+type TargetGroup struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+
+	VPC                *VPC
+	Port               *int64
+	Protocol           *string
+	HealthyThreshold   *int64
+	UnhealthyThreshold *int64
+	Tags               map[string]string
+
+	ARN *string
+}
+
+var _ fi.CloudupTask = &TargetGroup{}
+
+func (e *TargetGroup) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+func (e *TargetGroup) Find(c *fi.CloudupContext) (*TargetGroup, error) {
+	cloud := c.T.Cloud.(awsup.AWSCloud)
+
+	response, err := cloud.ELBV2().DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{
+		Names: []*string{e.Name},
+	})
+	if err != nil {
+		if awsup.AWSErrorCode(err) == elbv2.ErrCodeTargetGroupNotFoundException {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error listing TargetGroups: %v", err)
+	}
+	if response == nil || len(response.TargetGroups) == 0 {
+		return nil, nil
+	}
+
+	tg := response.TargetGroups[0]
+	return &TargetGroup{
+		Name:               e.Name,
+		Lifecycle:          e.Lifecycle,
+		VPC:                &VPC{ID: tg.VpcId},
+		Port:               tg.Port,
+		Protocol:           tg.Protocol,
+		HealthyThreshold:   tg.HealthyThresholdCount,
+		UnhealthyThreshold: tg.UnhealthyThresholdCount,
+		ARN:                tg.TargetGroupArn,
+	}, nil
+}
+
+func (e *TargetGroup) CheckChanges(a, ex, changes *TargetGroup) error {
+	if a != nil {
+		if changes.VPC != nil {
+			return fmt.Errorf("VPC cannot be changed on a TargetGroup after creation")
+		}
+		if changes.Protocol != nil {
+			return fmt.Errorf("Protocol cannot be changed on a TargetGroup after creation")
+		}
+	}
+	return nil
+}
+
+func (_ *TargetGroup) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *TargetGroup) error {
+	if a == nil {
+		request := &elbv2.CreateTargetGroupInput{
+			Name:                    e.Name,
+			VpcId:                   e.VPC.ID,
+			Port:                    e.Port,
+			Protocol:                e.Protocol,
+			HealthyThresholdCount:   e.HealthyThreshold,
+			UnhealthyThresholdCount: e.UnhealthyThreshold,
+		}
+		response, err := t.Cloud.ELBV2().CreateTargetGroup(request)
+		if err != nil {
+			return fmt.Errorf("error creating TargetGroup %q: %v", aws.StringValue(e.Name), err)
+		}
+		e.ARN = response.TargetGroups[0].TargetGroupArn
+
+		if err := t.AddELBV2Tags(aws.StringValue(e.ARN), e.Tags); err != nil {
+			return fmt.Errorf("error tagging TargetGroup %q: %v", aws.StringValue(e.Name), err)
+		}
+		return nil
+	}
+
+	if changes.HealthyThreshold != nil || changes.UnhealthyThreshold != nil {
+		_, err := t.Cloud.ELBV2().ModifyTargetGroup(&elbv2.ModifyTargetGroupInput{
+			TargetGroupArn:          a.ARN,
+			HealthyThresholdCount:   e.HealthyThreshold,
+			UnhealthyThresholdCount: e.UnhealthyThreshold,
+		})
+		if err != nil {
+			return fmt.Errorf("error updating TargetGroup %q: %v", aws.StringValue(e.Name), err)
+		}
+	}
+
+	return nil
+}
+
+// NetworkLoadBalancerListener maps one NLB listener port to a target group.
+type NetworkLoadBalancerListener struct {
+	TargetGroup *TargetGroup
+}
+
+// NetworkLoadBalancer manages an ELBv2 Network Load Balancer, used as an
+// alternative to a Classic ELB for fronting the bastion.
+type NetworkLoadBalancer struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+
+	LoadBalancerName *string
+	Subnets          []*Subnet
+	Listeners        map[string]*NetworkLoadBalancerListener
+	Tags             map[string]string
+
+	// SecurityGroups are attached to the NLB itself. Unlike a Classic ELB, traffic that
+	// arrives at an NLB keeps the original client source IP rather than being tagged with
+	// the NLB's security group, so these groups are what source-restricting rules (e.g.
+	// ssh-elb-to-bastion) actually need to match against at the target.
+	SecurityGroups []*SecurityGroup
+
+	ARN     *string
+	DNSName *string
+}
+
+var _ fi.CloudupTask = &NetworkLoadBalancer{}
+
+func (e *NetworkLoadBalancer) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+func (e *NetworkLoadBalancer) Find(c *fi.CloudupContext) (*NetworkLoadBalancer, error) {
+	cloud := c.T.Cloud.(awsup.AWSCloud)
+
+	response, err := cloud.ELBV2().DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{
+		Names: []*string{e.LoadBalancerName},
+	})
+	if err != nil {
+		if awsup.AWSErrorCode(err) == elbv2.ErrCodeLoadBalancerNotFoundException {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error listing NetworkLoadBalancers: %v", err)
+	}
+	if response == nil || len(response.LoadBalancers) == 0 {
+		return nil, nil
+	}
+
+	lb := response.LoadBalancers[0]
+	actual := &NetworkLoadBalancer{
+		Name:             e.Name,
+		Lifecycle:        e.Lifecycle,
+		LoadBalancerName: lb.LoadBalancerName,
+		ARN:              lb.LoadBalancerArn,
+		DNSName:          lb.DNSName,
+		Listeners:        e.Listeners,
+	}
+	for _, sg := range lb.SecurityGroups {
+		actual.SecurityGroups = append(actual.SecurityGroups, &SecurityGroup{ID: sg})
+	}
+	for _, az := range lb.AvailabilityZones {
+		actual.Subnets = append(actual.Subnets, &Subnet{ID: az.SubnetId})
+	}
+
+	return actual, nil
+}
+
+func (e *NetworkLoadBalancer) CheckChanges(a, ex, changes *NetworkLoadBalancer) error {
+	if a != nil {
+		if changes.LoadBalancerName != nil {
+			return fmt.Errorf("LoadBalancerName cannot be changed after a NetworkLoadBalancer is created")
+		}
+	}
+	return nil
+}
+
+func (_ *NetworkLoadBalancer) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *NetworkLoadBalancer) error {
+	if a == nil {
+		var subnetIDs []*string
+		for _, s := range e.Subnets {
+			subnetIDs = append(subnetIDs, s.ID)
+		}
+		var sgIDs []*string
+		for _, sg := range e.SecurityGroups {
+			sgIDs = append(sgIDs, sg.ID)
+		}
+
+		response, err := t.Cloud.ELBV2().CreateLoadBalancer(&elbv2.CreateLoadBalancerInput{
+			Name:           e.LoadBalancerName,
+			Type:           aws.String(elbv2.LoadBalancerTypeEnumNetwork),
+			Subnets:        subnetIDs,
+			SecurityGroups: sgIDs,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating NetworkLoadBalancer %q: %v", aws.StringValue(e.LoadBalancerName), err)
+		}
+
+		lb := response.LoadBalancers[0]
+		e.ARN = lb.LoadBalancerArn
+		e.DNSName = lb.DNSName
+
+		if err := t.AddELBV2Tags(aws.StringValue(e.ARN), e.Tags); err != nil {
+			return fmt.Errorf("error tagging NetworkLoadBalancer %q: %v", aws.StringValue(e.LoadBalancerName), err)
+		}
+
+		for port, listener := range e.Listeners {
+			if err := createNLBListener(t, e.ARN, port, listener); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if changes.SecurityGroups != nil {
+		var sgIDs []*string
+		for _, sg := range e.SecurityGroups {
+			sgIDs = append(sgIDs, sg.ID)
+		}
+		if _, err := t.Cloud.ELBV2().SetSecurityGroups(&elbv2.SetSecurityGroupsInput{
+			LoadBalancerArn: a.ARN,
+			SecurityGroups:  sgIDs,
+		}); err != nil {
+			return fmt.Errorf("error updating NetworkLoadBalancer %q security groups: %v", aws.StringValue(e.LoadBalancerName), err)
+		}
+	}
+
+	return nil
+}
+
+func createNLBListener(t *awsup.AWSAPITarget, lbARN *string, port string, listener *NetworkLoadBalancerListener) error {
+	portNum, err := strconv.ParseInt(port, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid NetworkLoadBalancer listener port %q: %v", port, err)
+	}
+	_, err = t.Cloud.ELBV2().CreateListener(&elbv2.CreateListenerInput{
+		LoadBalancerArn: lbARN,
+		Port:            aws.Int64(portNum),
+		Protocol:        aws.String(elbv2.ProtocolEnumTcp),
+		DefaultActions: []*elbv2.Action{
+			{
+				Type:           aws.String(elbv2.ActionTypeEnumForward),
+				TargetGroupArn: listener.TargetGroup.ARN,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating listener on port %s for NetworkLoadBalancer: %v", port, err)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// VPCEndpoint manages a VPC interface endpoint, e.g. for reaching the SSM
+// service privately so a bastion can be accessed via Session Manager without
+// any inbound SSH exposure.
+type VPCEndpoint struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+
+	VPC             *VPC
+	ServiceName     *string
+	VPCEndpointType *string
+	SecurityGroups  []*SecurityGroup
+	Subnets         []*Subnet
+	Tags            map[string]string
+
+	ID *string
+}
+
+var _ fi.CloudupTask = &VPCEndpoint{}
+
+func (e *VPCEndpoint) Run(c *fi.CloudupContext) error {
+	return fi.CloudupDefaultDeltaRunMethod(e, c)
+}
+
+func (e *VPCEndpoint) Find(c *fi.CloudupContext) (*VPCEndpoint, error) {
+	cloud := c.T.Cloud.(awsup.AWSCloud)
+
+	response, err := cloud.EC2().DescribeVpcEndpoints(&ec2.DescribeVpcEndpointsInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("vpc-id"), Values: []*string{e.VPC.ID}},
+			{Name: aws.String("service-name"), Values: []*string{e.ServiceName}},
+			{Name: aws.String("vpc-endpoint-state"), Values: aws.StringSlice([]string{"available", "pending"})},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing VPCEndpoints: %v", err)
+	}
+	if response == nil || len(response.VpcEndpoints) == 0 {
+		return nil, nil
+	}
+
+	vpce := response.VpcEndpoints[0]
+	actual := &VPCEndpoint{
+		Name:            e.Name,
+		Lifecycle:       e.Lifecycle,
+		VPC:             &VPC{ID: vpce.VpcId},
+		ServiceName:     vpce.ServiceName,
+		VPCEndpointType: vpce.VpcEndpointType,
+		ID:              vpce.VpcEndpointId,
+		Tags:            make(map[string]string),
+	}
+	for _, sg := range vpce.Groups {
+		actual.SecurityGroups = append(actual.SecurityGroups, &SecurityGroup{ID: sg.GroupId})
+	}
+	for _, subnetID := range vpce.SubnetIds {
+		actual.Subnets = append(actual.Subnets, &Subnet{ID: subnetID})
+	}
+	for _, tag := range vpce.Tags {
+		actual.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return actual, nil
+}
+
+func (e *VPCEndpoint) CheckChanges(a, ex, changes *VPCEndpoint) error {
+	if a != nil {
+		if changes.VPC != nil {
+			return fmt.Errorf("VPC cannot be changed on a VPCEndpoint after creation")
+		}
+		if changes.VPCEndpointType != nil {
+			return fmt.Errorf("VPCEndpointType cannot be changed on a VPCEndpoint after creation")
+		}
+	}
+	return nil
+}
+
+func (_ *VPCEndpoint) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *VPCEndpoint) error {
+	var sgIDs []*string
+	for _, sg := range e.SecurityGroups {
+		sgIDs = append(sgIDs, sg.ID)
+	}
+	var subnetIDs []*string
+	for _, s := range e.Subnets {
+		subnetIDs = append(subnetIDs, s.ID)
+	}
+
+	if a == nil {
+		response, err := t.Cloud.EC2().CreateVpcEndpoint(&ec2.CreateVpcEndpointInput{
+			VpcId:            e.VPC.ID,
+			ServiceName:      e.ServiceName,
+			VpcEndpointType:  e.VPCEndpointType,
+			SecurityGroupIds: sgIDs,
+			SubnetIds:        subnetIDs,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating VPCEndpoint %q: %v", aws.StringValue(e.Name), err)
+		}
+		e.ID = response.VpcEndpoint.VpcEndpointId
+
+		tags := t.Cloud.BuildTags(e.Name)
+		for k, v := range e.Tags {
+			tags[k] = v
+		}
+		if err := t.AddAWSTags(aws.StringValue(e.ID), tags); err != nil {
+			return fmt.Errorf("error tagging VPCEndpoint %q: %v", aws.StringValue(e.Name), err)
+		}
+		return nil
+	}
+
+	if changes.SecurityGroups != nil || changes.Subnets != nil {
+		_, err := t.Cloud.EC2().ModifyVpcEndpoint(&ec2.ModifyVpcEndpointInput{
+			VpcEndpointId:       a.ID,
+			AddSecurityGroupIds: sgIDs,
+			AddSubnetIds:        subnetIDs,
+		})
+		if err != nil {
+			return fmt.Errorf("error updating VPCEndpoint %q: %v", aws.StringValue(e.Name), err)
+		}
+	}
+
+	return nil
+}